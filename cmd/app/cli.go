@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// runCLI handles the one-shot subcommands, e.g.
+//
+//	device-api export --format parquet --out devices.parquet
+//	device-api import --format parquet --in devices.parquet
+func runCLI(repo repository.DeviceRepository, args []string, stdout io.Writer) error {
+	pt, ok := repo.(repository.ParquetTransfer)
+	if !ok {
+		return errors.New("storage backend does not support parquet transfer")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		format := fs.String("format", "parquet", "output format (parquet)")
+		out := fs.String("out", "devices.parquet", "output file path")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *format != "parquet" {
+			return fmt.Errorf("unsupported format %q", *format)
+		}
+		if err := pt.ExportParquet(context.Background(), *out); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "exported devices to %s\n", *out)
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ContinueOnError)
+		format := fs.String("format", "parquet", "input format (parquet)")
+		in := fs.String("in", "devices.parquet", "input file path")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *format != "parquet" {
+			return fmt.Errorf("unsupported format %q", *format)
+		}
+		n, err := pt.ImportParquet(context.Background(), *in)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "imported %d devices from %s\n", n, *in)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q (want export or import)", args[0])
+	}
+}
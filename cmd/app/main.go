@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
 
 	_ "github.com/marcboeker/go-duckdb"
 
+	"github.com/leandronowras/device-api/internal/delivery"
+	"github.com/leandronowras/device-api/internal/graphql"
+	igrpc "github.com/leandronowras/device-api/internal/grpc"
+	"github.com/leandronowras/device-api/internal/grpc/devicev1"
 	ih "github.com/leandronowras/device-api/internal/http"
+	"github.com/leandronowras/device-api/internal/outbox"
 	duckdbrepo "github.com/leandronowras/device-api/internal/repository/duckdb"
+	"github.com/leandronowras/device-api/internal/reservation"
+	"github.com/leandronowras/device-api/internal/webhook"
 )
 
 func main() {
@@ -26,8 +37,28 @@ func main() {
 	}
 
 	repo := duckdbrepo.NewDeviceRepository(db)
+
+	if len(os.Args) > 1 {
+		if err := runCLI(repo, os.Args[1:], os.Stdout); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	go reservation.NewWorker(repo, reservation.DefaultInterval).Run(context.Background())
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+		webhook.AllowPrivateTargets = true
+	}
+	go delivery.NewWorker(repo, delivery.DefaultInterval).Run(context.Background())
+
 	h := ih.NewHandler(repo)
 
+	sinks := []outbox.Sink{outbox.NewBusSink(h.Bus()), outbox.NewWebhookSink(repo)}
+	if os.Getenv("OUTBOX_STDOUT") == "true" {
+		sinks = append(sinks, outbox.NewWriterSink(os.Stdout))
+	}
+	go outbox.NewRelay(repo, outbox.DefaultInterval, sinks...).Run(context.Background())
+
 	r := chi.NewRouter()
 	r.Use(
 		middleware.RequestID,
@@ -36,13 +67,27 @@ func main() {
 		middleware.Recoverer,
 	)
 
-	r.Route("/v1", func(r chi.Router) {
-		r.Post("/devices", h.CreateDevice)
-		r.Get("/devices", h.ListDevices)
-		r.Get("/devices/{id}", h.GetDevice)
-		r.Patch("/devices/{id}", h.UpdateDevice)
-		r.Delete("/devices/{id}", h.DeleteDevice)
-	})
+	r.Route("/v1", h.Routes)
+	r.Handle("/graphql", graphql.NewHandler(repo, h.Bus()))
+	r.Get("/openapi.json", h.OpenAPI)
+	if os.Getenv("DOCS_UI") == "true" {
+		r.Get("/docs", h.Docs)
+	}
+
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen for gRPC: %v", err)
+	}
+	gs := grpc.NewServer()
+	devicev1.RegisterDeviceServiceServer(gs, igrpc.NewServer(repo, h.Bus()))
+	go func() {
+		log.Printf("gRPC DeviceService listening on %s", grpcAddr)
+		log.Fatal(gs.Serve(lis))
+	}()
 
 	addr := ":8080"
 	log.Printf("🚀 Device API running at http://localhost%s/v1/devices", addr)
@@ -0,0 +1,155 @@
+// Package delivery sends queued webhook deliveries.
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+// DefaultInterval is how often Run looks for deliveries that are due.
+const DefaultInterval = 5 * time.Second
+
+// batch caps the deliveries attempted per tick.
+const batch = 100
+
+// Worker POSTs due deliveries to their subscription's URL, signed with its
+// secret. A 2xx answer marks a delivery delivered; anything else, including
+// a timeout, schedules a retry with exponential backoff until
+// webhook.MaxAttempts is reached and the delivery is dead-lettered.
+type Worker struct {
+	repo     repository.DeviceRepository
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewWorker returns a worker polling every interval; zero means
+// DefaultInterval.
+func NewWorker(repo repository.DeviceRepository, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Worker{repo: repo, client: newClient(), interval: interval}
+}
+
+// newClient returns a client that only connects to addresses
+// webhook.CheckTargetIP allows. The check runs on the resolved address of
+// every connection, redirects included, so a host name cannot be pointed at
+// the internal network after the subscription was accepted.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return webhook.CheckTargetIP(ap.Addr())
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// Run ticks until ctx is cancelled. It does nothing if the repository does
+// not store webhooks.
+func (w *Worker) Run(ctx context.Context) {
+	if _, ok := w.repo.(repository.WebhookStore); !ok {
+		return
+	}
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		if err := w.Tick(ctx, time.Now()); err != nil {
+			log.Printf("webhook worker: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick attempts every delivery due at now, one at a time.
+func (w *Worker) Tick(ctx context.Context, now time.Time) error {
+	store, ok := w.repo.(repository.WebhookStore)
+	if !ok {
+		return nil
+	}
+	due, err := store.DueDeliveries(ctx, now, batch)
+	if err != nil {
+		return err
+	}
+
+	subs := map[string]*webhook.Subscription{}
+	for _, d := range due {
+		s, ok := subs[d.SubscriptionID]
+		if !ok {
+			s, err = store.Webhook(ctx, d.SubscriptionID)
+			if errors.Is(err, repository.ErrNotFound) {
+				// Deleted since the delivery was queued.
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			subs[d.SubscriptionID] = s
+		}
+
+		if code, err := w.send(ctx, s, d); err != nil {
+			d.Failed(code, err.Error(), now)
+		} else {
+			d.Succeeded(code, now)
+		}
+		if err := store.SaveDelivery(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send makes one attempt and returns the response code, or 0 when there was
+// no response. The signature carries the time of the attempt itself, not of
+// the tick, so receivers enforcing a replay window accept late sends of a
+// batch.
+func (w *Worker) send(ctx context.Context, s *webhook.Subscription, d *webhook.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "device-api-webhooks")
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Event-ID", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", d.ID)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Webhook-Signature", webhook.Signature(s.Secret, ts, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
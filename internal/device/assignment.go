@@ -0,0 +1,74 @@
+package device
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Assignment records one checkout of a device: who holds it, since when and
+// until when. CheckedInAt is nil while the device is still out.
+type Assignment struct {
+	ID           string
+	DeviceID     string
+	Assignee     string
+	CheckedOutAt time.Time
+	DueAt        time.Time
+	CheckedInAt  *time.Time
+}
+
+// Overdue reports whether the assignment is still open past its due date.
+func (a *Assignment) Overdue(now time.Time) bool {
+	return a.CheckedInAt == nil && now.After(a.DueAt)
+}
+
+// Checkout hands an available device to assignee until dueAt and moves it
+// to in-use. The returned assignment must be stored with the device.
+func (d *Device) Checkout(assignee string, dueAt, now time.Time) (*Assignment, error) {
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "" {
+		return nil, ErrRequired("assignee")
+	}
+	if dueAt.IsZero() {
+		return nil, ErrRequired("due_at")
+	}
+	if !dueAt.After(now) {
+		return nil, ErrInvalid("due_at", "due_at must be in the future", http.StatusBadRequest)
+	}
+	if d.state != StateAvailable {
+		return nil, ErrConflict("device", "device is "+d.state+"; only available devices can be checked out")
+	}
+
+	id, err := uuidNewString()
+	if err != nil {
+		return nil, &DomainError{
+			Code:    "internal_error",
+			Field:   "id",
+			Message: "failed to generate assignment ID: " + err.Error(),
+			HTTP:    http.StatusInternalServerError,
+		}
+	}
+	d.transition(StateInUse, now)
+	return &Assignment{
+		ID:           id,
+		DeviceID:     d.id,
+		Assignee:     assignee,
+		CheckedOutAt: now.UTC(),
+		DueAt:        dueAt.UTC(),
+	}, nil
+}
+
+// Checkin returns an in-use device, making it available again, and closes
+// its open assignment. open may be nil for devices put in use without a
+// checkout.
+func (d *Device) Checkin(open *Assignment, now time.Time) error {
+	if d.state != StateInUse {
+		return ErrConflict("device", "device is not checked out")
+	}
+	d.transition(StateAvailable, now)
+	if open != nil {
+		t := now.UTC()
+		open.CheckedInAt = &t
+	}
+	return nil
+}
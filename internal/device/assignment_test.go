@@ -0,0 +1,51 @@
+package device
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckoutCheckin(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	d, err := New("iPhone", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Checkout("alice", now.Add(-time.Hour), now); err == nil {
+		t.Fatal("expected error for a due date in the past")
+	}
+	a, err := d.Checkout(" alice ", now.Add(48*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if d.State() != StateInUse || a.Assignee != "alice" || a.DeviceID != d.ID() {
+		t.Fatalf("unexpected state %q / assignment %+v", d.State(), a)
+	}
+	if a.Overdue(now) || !a.Overdue(now.Add(72*time.Hour)) {
+		t.Fatal("Overdue should flip once the due date passes")
+	}
+
+	_, err = d.Checkout("bob", now.Add(time.Hour), now)
+	if derr, ok := err.(*DomainError); !ok || derr.HTTP != http.StatusConflict {
+		t.Fatalf("second checkout: want 409, got %v", err)
+	}
+
+	if err := d.SetState(StateAvailable, a); err == nil || d.State() != StateInUse {
+		t.Fatalf("a checked-out device left use without a check-in: %v", err)
+	}
+	if err := d.SetState(StateInUse, a); err != nil {
+		t.Fatalf("SetState(in-use) on a checked-out device: %v", err)
+	}
+
+	if err := d.Checkin(a, now.Add(time.Hour)); err != nil {
+		t.Fatalf("Checkin: %v", err)
+	}
+	if d.State() != StateAvailable || a.CheckedInAt == nil || a.Overdue(now.Add(72*time.Hour)) {
+		t.Fatalf("unexpected state %q / assignment %+v", d.State(), a)
+	}
+	if err := d.Checkin(nil, now); err == nil {
+		t.Fatal("expected error checking in an available device")
+	}
+}
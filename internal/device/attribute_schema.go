@@ -0,0 +1,197 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// AttributeSchema is the subset of JSON Schema used to constrain device
+// attributes:
+//
+//	{
+//	  "properties": {
+//	    "os_version":    {"type": "string", "pattern": "^[0-9.]+$"},
+//	    "purchase_date": {"type": "string", "format": "date"},
+//	    "cost":          {"type": "number", "minimum": 0}
+//	  },
+//	  "required": ["os_version"],
+//	  "additionalProperties": false
+//	}
+type AttributeSchema struct {
+	Type                 string                       `json:"type,omitempty"`
+	Properties           map[string]AttributeProperty `json:"properties,omitempty"`
+	Required             []string                     `json:"required,omitempty"`
+	AdditionalProperties *bool                        `json:"additionalProperties,omitempty"`
+}
+
+type AttributeProperty struct {
+	Type      string   `json:"type"` // string, number, integer or boolean
+	Format    string   `json:"format,omitempty"`
+	Enum      []any    `json:"enum,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// ParseAttributeSchema decodes and checks a schema document.
+func ParseAttributeSchema(raw []byte) (*AttributeSchema, error) {
+	var s AttributeSchema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, ErrInvalid("schema", "schema must be a JSON object: "+err.Error(), http.StatusBadRequest)
+	}
+	if s.Type != "" && s.Type != "object" {
+		return nil, ErrInvalid("schema", `schema type must be "object"`, http.StatusBadRequest)
+	}
+	for name, p := range s.Properties {
+		if !ValidAttributeKey(name) {
+			return nil, ErrInvalid("schema", fmt.Sprintf("property %q is not a valid attribute name", name), http.StatusBadRequest)
+		}
+		switch p.Type {
+		case "string", "number", "integer", "boolean":
+		default:
+			return nil, ErrInvalid("schema", fmt.Sprintf("property %q: type must be string, number, integer or boolean", name), http.StatusBadRequest)
+		}
+		switch p.Format {
+		case "", "date", "date-time":
+		default:
+			return nil, ErrInvalid("schema", fmt.Sprintf("property %q: unsupported format %q", name, p.Format), http.StatusBadRequest)
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return nil, ErrInvalid("schema", fmt.Sprintf("property %q: invalid pattern", name), http.StatusBadRequest)
+			}
+			p.re = re
+			s.Properties[name] = p
+		}
+	}
+	for _, name := range s.Required {
+		if !ValidAttributeKey(name) {
+			return nil, ErrInvalid("schema", fmt.Sprintf("required attribute %q is not a valid attribute name", name), http.StatusBadRequest)
+		}
+	}
+	return &s, nil
+}
+
+// Validate checks a (normalized) attribute set against the schema. sent
+// holds the attributes as the client wrote them, before normalization, so
+// string checks see "2025-01-01" rather than the time it was parsed into;
+// times kept from earlier writes are rendered back with attrTimeText.
+func (s *AttributeSchema) Validate(a Attributes, sent map[string]any) error {
+	for _, name := range s.Required {
+		if _, ok := a[name]; !ok {
+			return errAttribute(name, "attribute is required")
+		}
+	}
+	for _, name := range a.Keys() {
+		v := a[name]
+		p, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return errAttribute(name, "attribute is not allowed by the schema")
+			}
+			continue
+		}
+		if err := p.validate(name, v, sent[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p AttributeProperty) validate(name string, v, sent any) error {
+	switch p.Type {
+	case "string":
+		var str string
+		switch val := v.(type) {
+		case string:
+			if p.Format != "" {
+				return errAttribute(name, "must be a "+p.Format)
+			}
+			str = val
+		case time.Time:
+			if raw, ok := sent.(string); ok {
+				str = raw
+			} else {
+				str = attrTimeText(val, p.Format)
+			}
+		default:
+			return errAttribute(name, "must be a string")
+		}
+		if p.MinLength != nil && len(str) < *p.MinLength {
+			return errAttribute(name, fmt.Sprintf("must be at least %d characters", *p.MinLength))
+		}
+		if p.MaxLength != nil && len(str) > *p.MaxLength {
+			return errAttribute(name, fmt.Sprintf("must be at most %d characters", *p.MaxLength))
+		}
+		if p.re != nil && !p.re.MatchString(str) {
+			return errAttribute(name, "must match "+p.Pattern)
+		}
+	case "number", "integer":
+		f, ok := v.(float64)
+		if !ok {
+			return errAttribute(name, "must be a "+p.Type)
+		}
+		if p.Type == "integer" && f != float64(int64(f)) {
+			return errAttribute(name, "must be an integer")
+		}
+		if p.Minimum != nil && f < *p.Minimum {
+			return errAttribute(name, fmt.Sprintf("must be >= %v", *p.Minimum))
+		}
+		if p.Maximum != nil && f > *p.Maximum {
+			return errAttribute(name, fmt.Sprintf("must be <= %v", *p.Maximum))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return errAttribute(name, "must be a boolean")
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if enumEqual(e, v) {
+				return nil
+			}
+		}
+		return errAttribute(name, "must be one of the allowed values")
+	}
+	return nil
+}
+
+// attrTimeText renders a stored time the way it was most likely written:
+// as a date when the schema asks for one or it falls on midnight UTC.
+func attrTimeText(t time.Time, format string) string {
+	t = t.UTC()
+	if format == "date" || (format == "" && t.Equal(t.Truncate(24*time.Hour))) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func enumEqual(e, v any) bool {
+	if t, ok := v.(time.Time); ok {
+		s, ok := e.(string)
+		if !ok {
+			return false
+		}
+		et, ok := parseAttrString(s).(time.Time)
+		return ok && et.Equal(t)
+	}
+	return e == v
+}
+
+func errAttribute(name, reason string) *DomainError {
+	return &DomainError{
+		Code:    "invalid_attribute",
+		Field:   "attributes." + name,
+		Message: name + ": " + reason,
+		HTTP:    http.StatusUnprocessableEntity,
+	}
+}
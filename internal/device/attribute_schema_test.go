@@ -0,0 +1,126 @@
+package device
+
+import "testing"
+
+func TestAttributeSchemaValidate(t *testing.T) {
+	schema, err := ParseAttributeSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"os_version":    {"type": "string", "pattern": "^[0-9]+(\\.[0-9]+)*$"},
+			"purchase_date": {"type": "string", "format": "date"},
+			"cost":          {"type": "number", "minimum": 0},
+			"owner_team":    {"type": "string", "enum": ["qa", "ops"]},
+			"managed":       {"type": "boolean"}
+		},
+		"required": ["os_version"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		input    map[string]any
+		errField string // empty => valid
+	}{
+		{
+			name:  "accepts valid attributes",
+			input: map[string]any{"os_version": "17.1", "purchase_date": "2024-05-01", "cost": 999.0, "owner_team": "qa", "managed": true},
+		},
+		{
+			name:     "rejects missing required attribute",
+			input:    map[string]any{"cost": 1.0},
+			errField: "attributes.os_version",
+		},
+		{
+			name:     "rejects pattern mismatch",
+			input:    map[string]any{"os_version": "latest"},
+			errField: "attributes.os_version",
+		},
+		{
+			name:     "rejects non-date for date format",
+			input:    map[string]any{"os_version": "17", "purchase_date": "soon"},
+			errField: "attributes.purchase_date",
+		},
+		{
+			name:     "rejects value below minimum",
+			input:    map[string]any{"os_version": "17", "cost": -1.0},
+			errField: "attributes.cost",
+		},
+		{
+			name:     "rejects value outside enum",
+			input:    map[string]any{"os_version": "17", "owner_team": "sales"},
+			errField: "attributes.owner_team",
+		},
+		{
+			name:     "rejects wrong type",
+			input:    map[string]any{"os_version": "17", "managed": "yes"},
+			errField: "attributes.managed",
+		},
+		{
+			name:     "rejects additional property",
+			input:    map[string]any{"os_version": "17", "color": "red"},
+			errField: "attributes.color",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, err := NormalizeAttributes(tt.input)
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			err = schema.Validate(attrs, tt.input)
+			if tt.errField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			de, ok := err.(*DomainError)
+			if !ok {
+				t.Fatalf("expected *DomainError, got %T: %v", err, err)
+			}
+			if de.Field != tt.errField {
+				t.Fatalf("want error field %q, got %q (msg=%q)", tt.errField, de.Field, de.Message)
+			}
+		})
+	}
+}
+
+func TestNormalizeAttributesRejectsBadInput(t *testing.T) {
+	if _, err := NormalizeAttributes(map[string]any{"Bad-Key": "x"}); err == nil {
+		t.Fatalf("expected invalid key to be rejected")
+	}
+	if _, err := NormalizeAttributes(map[string]any{"nested": map[string]any{"a": 1}}); err == nil {
+		t.Fatalf("expected nested object to be rejected")
+	}
+}
+
+func TestAttributeSchemaPatternSeesWrittenDate(t *testing.T) {
+	schema, err := ParseAttributeSchema([]byte(`{
+		"properties": {"purchase_date": {"type": "string", "pattern": "^\\d{4}-\\d{2}-\\d{2}$"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := map[string]any{"purchase_date": "2025-01-01"}
+	attrs, err := NormalizeAttributes(sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(attrs, sent); err != nil {
+		t.Errorf("as sent: %v", err)
+	}
+	// Stored dates come back as times; they must still pass.
+	if err := schema.Validate(attrs, nil); err != nil {
+		t.Errorf("as stored: %v", err)
+	}
+
+	sent = map[string]any{"purchase_date": "2025-01-01T10:00:00Z"}
+	attrs, _ = NormalizeAttributes(sent)
+	if err := schema.Validate(attrs, sent); err == nil {
+		t.Error("date-time accepted by a date pattern")
+	}
+}
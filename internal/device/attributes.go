@@ -0,0 +1,109 @@
+package device
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Attributes holds free-form device metadata such as os_version or
+// purchase_date. Values are string, float64, bool or time.Time.
+type Attributes map[string]any
+
+// MaxAttributes caps how many attributes a device may carry.
+const MaxAttributes = 64
+
+var attrKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidAttributeKey reports whether k may be used as an attribute name.
+func ValidAttributeKey(k string) bool { return attrKeyRe.MatchString(k) }
+
+// NormalizeAttributes checks keys and converts decoded JSON values into the
+// supported types. Strings in RFC 3339 or YYYY-MM-DD form become times.
+func NormalizeAttributes(in map[string]any) (Attributes, error) {
+	if len(in) > MaxAttributes {
+		return nil, ErrInvalid("attributes", "at most 64 attributes are allowed", http.StatusBadRequest)
+	}
+	out := make(Attributes, len(in))
+	for k, v := range in {
+		if !ValidAttributeKey(k) {
+			return nil, ErrInvalid("attributes", `attribute name "`+k+`" must match [a-z][a-z0-9_]*`, http.StatusBadRequest)
+		}
+		switch val := v.(type) {
+		case string:
+			out[k] = parseAttrString(val)
+		case float64, bool, time.Time:
+			out[k] = val
+		case int:
+			out[k] = float64(val)
+		case json.Number:
+			f, err := val.Float64()
+			if err != nil {
+				return nil, ErrInvalid("attributes", `attribute "`+k+`" is not a valid number`, http.StatusBadRequest)
+			}
+			out[k] = f
+		default:
+			return nil, ErrInvalid("attributes", `attribute "`+k+`" must be a string, number, boolean or time`, http.StatusBadRequest)
+		}
+	}
+	return out, nil
+}
+
+func parseAttrString(s string) any {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC()
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t
+	}
+	return s
+}
+
+// Keys returns the attribute names in sorted order.
+func (a Attributes) Keys() []string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JSONValues returns the attributes with times as RFC 3339 strings, so
+// every value is a JSON string, number or boolean and round-trips through
+// NormalizeAttributes.
+func (a Attributes) JSONValues() map[string]any {
+	m := make(map[string]any, len(a))
+	for k, v := range a {
+		if t, ok := v.(time.Time); ok {
+			m[k] = t.UTC().Format(time.RFC3339Nano)
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// MarshalJSON writes the attributes as JSONValues.
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.JSONValues())
+}
+
+func (d *Device) Attributes() Attributes {
+	out := make(Attributes, len(d.attributes))
+	for k, v := range d.attributes {
+		out[k] = v
+	}
+	return out
+}
+
+// SetAttributes replaces every attribute. Use NormalizeAttributes first for
+// untrusted input.
+func (d *Device) SetAttributes(a Attributes) {
+	d.attributes = make(Attributes, len(a))
+	for k, v := range a {
+		d.attributes[k] = v
+	}
+}
@@ -13,12 +13,19 @@ type Device struct {
 	brand         string
 	state         string
 	creation_time time.Time
+	attributes    Attributes
+	tags          map[string]struct{}
+	serialNumber  string
+	// events are recorded changes not yet pulled; see PullEvents.
+	events []Event
 }
 
 const (
 	StateAvailable = "available"
 	StateInUse     = "in-use"
 	StateInactive  = "inactive"
+	// StateMaintenance is entered and left only through maintenance tickets.
+	StateMaintenance = "maintenance"
 )
 
 func New(name, brand string, stateOptional ...string) (*Device, error) {
@@ -36,7 +43,7 @@ func New(name, brand string, stateOptional ...string) (*Device, error) {
 	if brand == "" {
 		return nil, ErrRequired("brand")
 	}
-	if !isValidState(state) {
+	if !isValidState(state) || state == StateMaintenance {
 		return nil, ErrInvalid("state", "state must be one of: available, in-use, inactive", http.StatusBadRequest)
 	}
 
@@ -52,16 +59,30 @@ func New(name, brand string, stateOptional ...string) (*Device, error) {
 
 	createdAt := time.Now().UTC()
 
-	return &Device{
+	d := &Device{
 		id:            id,
 		name:          name,
 		brand:         brand,
 		state:         state,
 		creation_time: createdAt,
-	}, nil
+	}
+	d.record(DeviceCreated{DeviceID: id, Name: name, Brand: brand, State: state, OccurredAt: createdAt})
+	return d, nil
 }
 
+// NewWithID builds a device with a caller-chosen ID, e.g. from an import.
+// Like New it refuses maintenance: only a ticket may put a device there,
+// and a device without one could never leave it.
 func NewWithID(id, name, brand, state string, creationTime time.Time) (*Device, error) {
+	if strings.EqualFold(strings.TrimSpace(state), StateMaintenance) {
+		return nil, ErrInvalid("state", "state must be one of: available, in-use, inactive", http.StatusBadRequest)
+	}
+	return Restore(id, name, brand, state, creationTime)
+}
+
+// Restore rebuilds a stored device, validating its values. Unlike NewWithID
+// it accepts maintenance, which the device's open ticket accounts for.
+func Restore(id, name, brand, state string, creationTime time.Time) (*Device, error) {
 	name = strings.TrimSpace(name)
 	brand = strings.TrimSpace(brand)
 	state = strings.ToLower(strings.TrimSpace(state))
@@ -85,6 +106,19 @@ func NewWithID(id, name, brand, state string, creationTime time.Time) (*Device,
 	}, nil
 }
 
+// Rehydrate rebuilds a device from stored values without validating them.
+// It exists for read-only projections that load only some columns; use
+// Restore for anything that may be written back.
+func Rehydrate(id, name, brand, state string, creationTime time.Time) *Device {
+	return &Device{
+		id:            id,
+		name:          name,
+		brand:         brand,
+		state:         state,
+		creation_time: creationTime,
+	}
+}
+
 // Stub to keep this snippet standalone; swap with "github.com/google/uuid".
 func uuidNewString() (string, error) {
 	u, err := uuid.NewRandom()
@@ -108,7 +142,7 @@ func (d *Device) ValidateInvariants() error {
 
 func isValidState(s string) bool {
 	switch s {
-	case StateAvailable, StateInUse, StateInactive:
+	case StateAvailable, StateInUse, StateInactive, StateMaintenance:
 		return true
 	default:
 		return false
@@ -159,29 +193,59 @@ func (d *Device) Brand() string           { return d.brand }
 func (d *Device) State() string           { return d.state }
 func (d *Device) CreationTime() time.Time { return d.creation_time }
 
+// SetName renames the device. The name is frozen while the device is in
+// use.
 func (d *Device) SetName(name string) error {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return ErrRequired("name")
 	}
+	if name == d.name {
+		return nil
+	}
+	if d.state == StateInUse {
+		return errInUse()
+	}
+	d.record(DeviceRenamed{
+		DeviceID: d.id, OldName: d.name, Name: name, OldBrand: d.brand, Brand: d.brand, OccurredAt: time.Now().UTC(),
+	})
 	d.name = name
 	return nil
 }
 
+// SetBrand changes the brand, which is frozen while the device is in use.
 func (d *Device) SetBrand(brand string) error {
 	brand = strings.TrimSpace(brand)
 	if brand == "" {
 		return ErrRequired("brand")
 	}
+	if brand == d.brand {
+		return nil
+	}
+	if d.state == StateInUse {
+		return errInUse()
+	}
+	d.record(DeviceRenamed{
+		DeviceID: d.id, OldName: d.name, Name: d.name, OldBrand: d.brand, Brand: brand, OccurredAt: time.Now().UTC(),
+	})
 	d.brand = brand
 	return nil
 }
 
-func (d *Device) SetState(state string) error {
+// SetState moves the device to state. open is the device's open assignment,
+// or nil: a checked-out device stays in use until it is checked in, so its
+// assignment is never left open on a device that is not.
+func (d *Device) SetState(state string, open *Assignment) error {
 	state = strings.ToLower(strings.TrimSpace(state))
-	if !isValidState(state) {
+	if !isValidState(state) || state == StateMaintenance {
 		return ErrInvalid("state", "state must be one of: available, in-use, inactive", http.StatusBadRequest)
 	}
-	d.state = state
+	if d.state == StateMaintenance {
+		return ErrForbiddenChange("state", "device is in maintenance; close its ticket first", http.StatusConflict)
+	}
+	if open != nil && state != StateInUse {
+		return ErrForbiddenChange("state", "device is checked out; check it in first", http.StatusConflict)
+	}
+	d.transition(state, time.Now())
 	return nil
 }
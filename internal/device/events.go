@@ -0,0 +1,97 @@
+package device
+
+import (
+	"net/http"
+	"time"
+)
+
+// Event is a domain event a Device records as its methods change it.
+// Whoever persists the device drains them with PullEvents.
+type Event interface {
+	// EventName is the event's type name, e.g. "DeviceRenamed".
+	EventName() string
+	// AggregateID is the ID of the device the event is about.
+	AggregateID() string
+}
+
+// DeviceCreated is recorded by New.
+type DeviceCreated struct {
+	DeviceID   string    `json:"device_id"`
+	Name       string    `json:"name"`
+	Brand      string    `json:"brand"`
+	State      string    `json:"state"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// DeviceRenamed is recorded when the name or the brand changes; the other
+// pair of fields then holds the same value twice.
+type DeviceRenamed struct {
+	DeviceID   string    `json:"device_id"`
+	OldName    string    `json:"old_name"`
+	Name       string    `json:"name"`
+	OldBrand   string    `json:"old_brand"`
+	Brand      string    `json:"brand"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// DeviceStateChanged is recorded on every state transition, whether by
+// SetState, a checkout, a reservation or a maintenance ticket.
+type DeviceStateChanged struct {
+	DeviceID   string    `json:"device_id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// DeviceDeleted is recorded by Delete.
+type DeviceDeleted struct {
+	DeviceID   string    `json:"device_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (DeviceCreated) EventName() string      { return "DeviceCreated" }
+func (DeviceRenamed) EventName() string      { return "DeviceRenamed" }
+func (DeviceStateChanged) EventName() string { return "DeviceStateChanged" }
+func (DeviceDeleted) EventName() string      { return "DeviceDeleted" }
+
+func (e DeviceCreated) AggregateID() string      { return e.DeviceID }
+func (e DeviceRenamed) AggregateID() string      { return e.DeviceID }
+func (e DeviceStateChanged) AggregateID() string { return e.DeviceID }
+func (e DeviceDeleted) AggregateID() string      { return e.DeviceID }
+
+// PullEvents returns the events recorded since the last call, oldest first,
+// and forgets them.
+func (d *Device) PullEvents() []Event {
+	events := d.events
+	d.events = nil
+	return events
+}
+
+func (d *Device) record(e Event) {
+	d.events = append(d.events, e)
+}
+
+// transition moves the device to state, recording the change if there is
+// one. Callers have already checked that the move is allowed.
+func (d *Device) transition(state string, now time.Time) {
+	if d.state == state {
+		return
+	}
+	d.record(DeviceStateChanged{DeviceID: d.id, From: d.state, To: state, OccurredAt: now.UTC()})
+	d.state = state
+}
+
+// Delete checks that the device may be deleted and records DeviceDeleted.
+// Devices in use cannot be deleted.
+func (d *Device) Delete(now time.Time) error {
+	if d.state == StateInUse {
+		return ErrConflict("device", "cannot delete device in use")
+	}
+	d.record(DeviceDeleted{DeviceID: d.id, OccurredAt: now.UTC()})
+	return nil
+}
+
+// errInUse rejects name and brand changes while the device is in use.
+func errInUse() *DomainError {
+	return ErrForbiddenChange("name/brand", "device is in use", http.StatusBadRequest)
+}
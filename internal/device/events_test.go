@@ -0,0 +1,101 @@
+package device
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.EventName()
+	}
+	return names
+}
+
+func TestDeviceRecordsEvents(t *testing.T) {
+	d, err := New("iPhone", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := d.PullEvents()
+	if len(events) != 1 {
+		t.Fatalf("events after New = %v", eventNames(events))
+	}
+	if c, ok := events[0].(DeviceCreated); !ok || c.DeviceID != d.ID() || c.State != StateAvailable {
+		t.Fatalf("unexpected %#v", events[0])
+	}
+	if len(d.PullEvents()) != 0 {
+		t.Fatal("PullEvents did not forget the events")
+	}
+
+	// Unchanged values record nothing.
+	_ = d.SetName("iPhone")
+	_ = d.SetState(StateAvailable, nil)
+	if got := d.PullEvents(); len(got) != 0 {
+		t.Fatalf("no-op changes recorded %v", eventNames(got))
+	}
+
+	_ = d.SetName("iPhone 15")
+	_ = d.SetState(StateInactive, nil)
+	events = d.PullEvents()
+	if len(events) != 2 {
+		t.Fatalf("events = %v", eventNames(events))
+	}
+	if r := events[0].(DeviceRenamed); r.OldName != "iPhone" || r.Name != "iPhone 15" || r.Brand != "Apple" {
+		t.Fatalf("unexpected %#v", r)
+	}
+	if s := events[1].(DeviceStateChanged); s.From != StateAvailable || s.To != StateInactive {
+		t.Fatalf("unexpected %#v", s)
+	}
+
+	if err := d.Delete(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if events = d.PullEvents(); len(events) != 1 || events[0].EventName() != "DeviceDeleted" {
+		t.Fatalf("events after Delete = %v", eventNames(events))
+	}
+}
+
+func TestCheckoutRecordsStateChange(t *testing.T) {
+	d, _ := New("Pixel", "Google")
+	d.PullEvents()
+	now := time.Now()
+	a, err := d.Checkout("alice@example.com", now.Add(time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Checkin(a, now); err != nil {
+		t.Fatal(err)
+	}
+	events := d.PullEvents()
+	if len(events) != 2 {
+		t.Fatalf("events = %v", eventNames(events))
+	}
+	if s := events[1].(DeviceStateChanged); s.From != StateInUse || s.To != StateAvailable {
+		t.Fatalf("unexpected %#v", s)
+	}
+}
+
+func TestInUseRulesLiveInTheAggregate(t *testing.T) {
+	d, _ := New("iPhone", "Apple", StateInUse)
+	d.PullEvents()
+
+	var derr *DomainError
+	if err := d.SetName("iPhone 15"); !errors.As(err, &derr) || derr.Code != "forbidden_change" {
+		t.Fatalf("SetName in use: %v", err)
+	}
+	if err := d.SetBrand("Samsung"); !errors.As(err, &derr) || derr.Code != "forbidden_change" {
+		t.Fatalf("SetBrand in use: %v", err)
+	}
+	if err := d.Delete(time.Now()); !errors.As(err, &derr) || derr.Code != "conflict_device" {
+		t.Fatalf("Delete in use: %v", err)
+	}
+	if got := d.PullEvents(); len(got) != 0 {
+		t.Fatalf("rejected changes recorded %v", eventNames(got))
+	}
+	if d.Name() != "iPhone" || d.Brand() != "Apple" {
+		t.Fatal("rejected change applied")
+	}
+}
@@ -0,0 +1,108 @@
+package device
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxTicketText caps ticket descriptions and resolutions.
+const maxTicketText = 2000
+
+// MaintenanceTicket is the service record of one repair or inspection.
+// While it is open the device is in maintenance; closing it restores
+// PreviousState.
+type MaintenanceTicket struct {
+	ID            string
+	DeviceID      string
+	Description   string
+	Resolution    string
+	Cost          *float64
+	PreviousState string
+	OpenedAt      time.Time
+	ClosedAt      *time.Time
+}
+
+// Open reports whether the ticket has not been closed yet.
+func (t *MaintenanceTicket) Open() bool { return t.ClosedAt == nil }
+
+// OpenMaintenance files a ticket and moves the device to maintenance, which
+// blocks checkouts and reservations until the ticket is closed. cost may be
+// nil when not yet known.
+func (d *Device) OpenMaintenance(description string, cost *float64, now time.Time) (*MaintenanceTicket, error) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return nil, ErrRequired("description")
+	}
+	if len(description) > maxTicketText {
+		return nil, ErrInvalid("description", "description must be at most 2000 characters", http.StatusBadRequest)
+	}
+	cost, err := validCost(cost)
+	if err != nil {
+		return nil, err
+	}
+	if d.state == StateMaintenance {
+		return nil, ErrConflict("device", "device already has an open maintenance ticket")
+	}
+
+	id, err := uuidNewString()
+	if err != nil {
+		return nil, &DomainError{
+			Code:    "internal_error",
+			Field:   "id",
+			Message: "failed to generate ticket ID: " + err.Error(),
+			HTTP:    http.StatusInternalServerError,
+		}
+	}
+	t := &MaintenanceTicket{
+		ID:            id,
+		DeviceID:      d.id,
+		Description:   description,
+		Cost:          cost,
+		PreviousState: d.state,
+		OpenedAt:      now.UTC(),
+	}
+	d.transition(StateMaintenance, now)
+	return t, nil
+}
+
+// CloseMaintenance closes t and returns the device to the state it had when
+// the ticket was opened. A non-nil cost replaces the one recorded so far.
+func (d *Device) CloseMaintenance(t *MaintenanceTicket, resolution string, cost *float64, now time.Time) error {
+	if !t.Open() {
+		return ErrConflict("ticket", "ticket is already closed")
+	}
+	resolution = strings.TrimSpace(resolution)
+	if len(resolution) > maxTicketText {
+		return ErrInvalid("resolution", "resolution must be at most 2000 characters", http.StatusBadRequest)
+	}
+	cost, err := validCost(cost)
+	if err != nil {
+		return err
+	}
+
+	if cost != nil {
+		t.Cost = cost
+	}
+	t.Resolution = resolution
+	closed := now.UTC()
+	t.ClosedAt = &closed
+	if d.state == StateMaintenance {
+		d.transition(t.PreviousState, now)
+	}
+	return nil
+}
+
+// validCost rounds a cost to cents and rejects negative or non-finite ones.
+func validCost(cost *float64) (*float64, error) {
+	if cost == nil {
+		return nil, nil
+	}
+	c := *cost
+	if math.IsNaN(c) || math.IsInf(c, 0) || c < 0 {
+		return nil, ErrInvalid("cost", "cost must be a non-negative amount", http.StatusBadRequest)
+	}
+	c = math.Round(c*100) / 100
+	return &c, nil
+}
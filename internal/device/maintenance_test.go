@@ -0,0 +1,62 @@
+package device
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceRestoresPreviousState(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	d, err := New("ThinkPad", "Lenovo", StateInactive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cost := 120.456
+	ticket, err := d.OpenMaintenance("cracked screen", &cost, now)
+	if err != nil {
+		t.Fatalf("OpenMaintenance: %v", err)
+	}
+	if d.State() != StateMaintenance || ticket.PreviousState != StateInactive || *ticket.Cost != 120.46 {
+		t.Fatalf("unexpected state %q / ticket %+v", d.State(), ticket)
+	}
+	if _, err := d.OpenMaintenance("again", nil, now); err == nil {
+		t.Fatal("expected conflict opening a second ticket")
+	}
+	if err := d.SetState(StateAvailable, nil); err == nil {
+		t.Fatal("expected SetState to refuse leaving maintenance")
+	}
+	if _, err := d.Checkout("alice", now.Add(time.Hour), now); err == nil {
+		t.Fatal("expected checkout to be blocked during maintenance")
+	}
+
+	if err := d.CloseMaintenance(ticket, "screen replaced", nil, now.Add(time.Hour)); err != nil {
+		t.Fatalf("CloseMaintenance: %v", err)
+	}
+	if d.State() != StateInactive || ticket.Open() || *ticket.Cost != 120.46 {
+		t.Fatalf("unexpected state %q / ticket %+v", d.State(), ticket)
+	}
+	if err := d.CloseMaintenance(ticket, "", nil, now); err == nil {
+		t.Fatal("expected conflict closing a closed ticket")
+	}
+}
+
+func TestMaintenanceStateIsNotSettable(t *testing.T) {
+	if _, err := New("ThinkPad", "Lenovo", StateMaintenance); err == nil {
+		t.Fatal("expected New to reject the maintenance state")
+	}
+	if _, err := NewWithID("id-1", "ThinkPad", "Lenovo", " Maintenance ", time.Now()); err == nil {
+		t.Fatal("expected NewWithID to reject the maintenance state")
+	}
+	if _, err := Restore("id-1", "ThinkPad", "Lenovo", StateMaintenance, time.Now()); err != nil {
+		t.Fatalf("Restore must load devices under maintenance: %v", err)
+	}
+	d, _ := New("ThinkPad", "Lenovo")
+	if err := d.SetState(StateMaintenance, nil); err == nil {
+		t.Fatal("expected SetState to reject the maintenance state")
+	}
+	negative := -1.0
+	if _, err := d.OpenMaintenance("battery", &negative, time.Now()); err == nil {
+		t.Fatal("expected error for a negative cost")
+	}
+}
@@ -0,0 +1,175 @@
+package device
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reservation statuses. Scheduled and active reservations block their time
+// slot; the others are history.
+const (
+	ReservationScheduled = "scheduled"
+	ReservationActive    = "active"
+	ReservationCompleted = "completed"
+	ReservationCancelled = "cancelled"
+	// ReservationMissed marks a reservation that ended without starting
+	// because the device never became available.
+	ReservationMissed = "missed"
+)
+
+// MaxReservation caps how long one booking may hold a device.
+const MaxReservation = 30 * 24 * time.Hour
+
+// Reservation books a device for [StartsAt, EndsAt). While it is active the
+// device is in use.
+type Reservation struct {
+	ID          string
+	DeviceID    string
+	Reserver    string
+	Note        string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Status      string
+	CreatedAt   time.Time
+	CancelledAt *time.Time
+}
+
+// NewReservation validates a booking request for deviceID.
+func NewReservation(deviceID, reserver, note string, startsAt, endsAt, now time.Time) (*Reservation, error) {
+	reserver = strings.TrimSpace(reserver)
+	if reserver == "" {
+		return nil, ErrRequired("reserver")
+	}
+	if startsAt.IsZero() {
+		return nil, ErrRequired("starts_at")
+	}
+	if endsAt.IsZero() {
+		return nil, ErrRequired("ends_at")
+	}
+	if !endsAt.After(startsAt) {
+		return nil, ErrInvalid("ends_at", "ends_at must be after starts_at", http.StatusBadRequest)
+	}
+	if !endsAt.After(now) {
+		return nil, ErrInvalid("ends_at", "ends_at must be in the future", http.StatusBadRequest)
+	}
+	if endsAt.Sub(startsAt) > MaxReservation {
+		return nil, ErrInvalid("ends_at", "a reservation may last at most 30 days", http.StatusBadRequest)
+	}
+
+	id, err := uuidNewString()
+	if err != nil {
+		return nil, &DomainError{
+			Code:    "internal_error",
+			Field:   "id",
+			Message: "failed to generate reservation ID: " + err.Error(),
+			HTTP:    http.StatusInternalServerError,
+		}
+	}
+	return &Reservation{
+		ID:        id,
+		DeviceID:  deviceID,
+		Reserver:  reserver,
+		Note:      strings.TrimSpace(note),
+		StartsAt:  startsAt.UTC(),
+		EndsAt:    endsAt.UTC(),
+		Status:    ReservationScheduled,
+		CreatedAt: now.UTC(),
+	}, nil
+}
+
+// Blocking reports whether the reservation still holds its time slot.
+func (r *Reservation) Blocking() bool {
+	return r.Status == ReservationScheduled || r.Status == ReservationActive
+}
+
+// Cancel withdraws the reservation. Cancelling an active one ends it early
+// and hands d, the reserved device, back; open is d's open assignment, if
+// any (see releaseReserved).
+func (r *Reservation) Cancel(d *Device, open *Assignment, now time.Time) error {
+	switch r.Status {
+	case ReservationScheduled:
+	case ReservationActive:
+		d.releaseReserved(open, now)
+	default:
+		return ErrConflict("reservation", "reservation is already "+r.Status)
+	}
+	r.Status = ReservationCancelled
+	t := now.UTC()
+	r.CancelledAt = &t
+	return nil
+}
+
+// StartReservation puts the device in use for a scheduled reservation. It
+// fails with a conflict while the device is not available, e.g. because an
+// earlier checkout has not been returned.
+func (d *Device) StartReservation(r *Reservation) error {
+	if r.Status != ReservationScheduled {
+		return ErrConflict("reservation", "reservation is "+r.Status)
+	}
+	if d.state != StateAvailable {
+		return ErrConflict("device", "device is "+d.state+"; reservation cannot start")
+	}
+	d.transition(StateInUse, time.Now())
+	r.Status = ReservationActive
+	return nil
+}
+
+// EndReservation closes a reservation whose time is up: an active one
+// completes and releases the device, a scheduled one is marked missed. open
+// is the device's open assignment, if any (see releaseReserved).
+func (d *Device) EndReservation(r *Reservation, open *Assignment) error {
+	switch r.Status {
+	case ReservationActive:
+		d.releaseReserved(open, time.Now())
+		r.Status = ReservationCompleted
+	case ReservationScheduled:
+		r.Status = ReservationMissed
+	default:
+		return ErrConflict("reservation", "reservation is already "+r.Status)
+	}
+	return nil
+}
+
+// releaseReserved makes the device available when an active reservation
+// ends. The device may have been checked in and out again during the
+// reservation, so it is only released while no assignment is open;
+// otherwise it stays with the assignee.
+func (d *Device) releaseReserved(open *Assignment, now time.Time) {
+	if d.state == StateInUse && open == nil {
+		d.transition(StateAvailable, now)
+	}
+}
+
+// Interval is a half-open time range [Start, End).
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FreeIntervals returns the parts of [from, to) not covered by busy. The
+// busy intervals may overlap and extend past the window.
+func FreeIntervals(from, to time.Time, busy []Interval) []Interval {
+	sorted := append([]Interval(nil), busy...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var free []Interval
+	cursor := from
+	for _, b := range sorted {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if !b.Start.Before(to) {
+			break
+		}
+		if b.Start.After(cursor) {
+			free = append(free, Interval{Start: cursor, End: b.Start})
+		}
+		cursor = b.End
+	}
+	if cursor.Before(to) {
+		free = append(free, Interval{Start: cursor, End: to})
+	}
+	return free
+}
@@ -0,0 +1,110 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReservationLifecycle(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	d, err := New("Pixel", "Google")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewReservation(d.ID(), "qa", "", now.Add(2*time.Hour), now.Add(time.Hour), now); err == nil {
+		t.Fatal("expected error when ends_at precedes starts_at")
+	}
+	if _, err := NewReservation(d.ID(), "qa", "", now, now.Add(31*24*time.Hour), now); err == nil {
+		t.Fatal("expected error past MaxReservation")
+	}
+
+	r, err := NewReservation(d.ID(), " qa ", "", now.Add(time.Hour), now.Add(3*time.Hour), now)
+	if err != nil {
+		t.Fatalf("NewReservation: %v", err)
+	}
+	if r.Status != ReservationScheduled || r.Reserver != "qa" || !r.Blocking() {
+		t.Fatalf("unexpected reservation %+v", r)
+	}
+
+	if err := d.StartReservation(r); err != nil {
+		t.Fatalf("StartReservation: %v", err)
+	}
+	if d.State() != StateInUse || r.Status != ReservationActive {
+		t.Fatalf("after start: state %q, status %q", d.State(), r.Status)
+	}
+	if err := d.EndReservation(r, nil); err != nil {
+		t.Fatalf("EndReservation: %v", err)
+	}
+	if d.State() != StateAvailable || r.Status != ReservationCompleted || r.Blocking() {
+		t.Fatalf("after end: state %q, status %q", d.State(), r.Status)
+	}
+	if err := r.Cancel(d, nil, now); err == nil {
+		t.Fatal("expected error cancelling a completed reservation")
+	}
+}
+
+func TestStartReservationNeedsAvailableDevice(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	d, _ := New("Pixel", "Google", StateInUse)
+	r, _ := NewReservation(d.ID(), "qa", "", now, now.Add(time.Hour), now)
+
+	if err := d.StartReservation(r); err == nil {
+		t.Fatal("expected conflict starting on an in-use device")
+	}
+	if err := d.EndReservation(r, nil); err != nil || r.Status != ReservationMissed {
+		t.Fatalf("EndReservation: err=%v status=%q", err, r.Status)
+	}
+}
+
+func TestFreeIntervals(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2025, 3, 1, h, 0, 0, 0, time.UTC) }
+	busy := []Interval{
+		{Start: at(14), End: at(16)},
+		{Start: at(7), End: at(10)}, // starts before the window
+		{Start: at(9), End: at(11)}, // overlaps the previous one
+		{Start: at(17), End: at(20)},
+	}
+	got := FreeIntervals(at(8), at(18), busy)
+	want := []Interval{
+		{Start: at(11), End: at(14)},
+		{Start: at(16), End: at(17)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FreeIntervals = %v, want %v", got, want)
+	}
+	if got := FreeIntervals(at(8), at(9), nil); !reflect.DeepEqual(got, []Interval{{Start: at(8), End: at(9)}}) {
+		t.Fatalf("empty busy list: %v", got)
+	}
+}
+
+func TestEndingReservationKeepsCheckedOutDevice(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	d, _ := New("Pixel", "Google")
+	r, _ := NewReservation(d.ID(), "qa", "", now, now.Add(time.Hour), now)
+	if err := d.StartReservation(r); err != nil {
+		t.Fatal(err)
+	}
+	// Checked in during the reservation, then out to someone else.
+	if err := d.Checkin(nil, now); err != nil {
+		t.Fatal(err)
+	}
+	a, err := d.Checkout("bob", now.Add(2*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.EndReservation(r, a); err != nil {
+		t.Fatal(err)
+	}
+	if d.State() != StateInUse || r.Status != ReservationCompleted {
+		t.Fatalf("after end: state %q, status %q", d.State(), r.Status)
+	}
+
+	r2, _ := NewReservation(d.ID(), "qa", "", now, now.Add(time.Hour), now)
+	r2.Status = ReservationActive
+	if err := r2.Cancel(d, a, now); err != nil || d.State() != StateInUse {
+		t.Fatalf("after cancel: err=%v state %q", err, d.State())
+	}
+}
@@ -0,0 +1,37 @@
+package device
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// serialRe keeps serial numbers usable as a URL path segment.
+var serialRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+
+// NormalizeSerialNumber trims a manufacturer serial number or asset tag and
+// checks its shape. An empty serial number is valid and means "none".
+func NormalizeSerialNumber(serial string) (string, error) {
+	s := strings.TrimSpace(serial)
+	if s == "" {
+		return "", nil
+	}
+	if !serialRe.MatchString(s) {
+		return "", ErrInvalid("serial_number", `serial_number must be 1-64 characters of letters, digits, ".", "_", ":" or "-"`, http.StatusBadRequest)
+	}
+	return s, nil
+}
+
+// SerialNumber returns the optional external identifier. Serial numbers are
+// unique per brand, compared case-insensitively; the repository enforces it.
+func (d *Device) SerialNumber() string { return d.serialNumber }
+
+// SetSerialNumber sets the serial number; an empty value clears it.
+func (d *Device) SetSerialNumber(serial string) error {
+	s, err := NormalizeSerialNumber(serial)
+	if err != nil {
+		return err
+	}
+	d.serialNumber = s
+	return nil
+}
@@ -0,0 +1,27 @@
+package device
+
+import "testing"
+
+func TestSetSerialNumber(t *testing.T) {
+	d, err := New("iPhone", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetSerialNumber("  C02XK1-ABC.9 "); err != nil {
+		t.Fatalf("SetSerialNumber: %v", err)
+	}
+	if got := d.SerialNumber(); got != "C02XK1-ABC.9" {
+		t.Fatalf("SerialNumber() = %q", got)
+	}
+	for _, bad := range []string{"has space", "a/b", "-leading"} {
+		if err := d.SetSerialNumber(bad); err == nil {
+			t.Errorf("SetSerialNumber(%q): expected error", bad)
+		}
+	}
+	if got := d.SerialNumber(); got != "C02XK1-ABC.9" {
+		t.Fatalf("rejected value changed serial to %q", got)
+	}
+	if err := d.SetSerialNumber(""); err != nil || d.SerialNumber() != "" {
+		t.Fatalf("clearing serial: err=%v serial=%q", err, d.SerialNumber())
+	}
+}
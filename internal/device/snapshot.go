@@ -0,0 +1,30 @@
+package device
+
+import "time"
+
+// Snapshot is the serialized form of a device: the API's device
+// representation and the payload of device events.
+type Snapshot struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Brand        string     `json:"brand"`
+	SerialNumber string     `json:"serial_number,omitempty"`
+	State        string     `json:"state"`
+	CreationTime time.Time  `json:"creation_time"`
+	Attributes   Attributes `json:"attributes"`
+	Tags         []string   `json:"tags"`
+}
+
+// Snapshot captures the device's current values.
+func (d *Device) Snapshot() Snapshot {
+	return Snapshot{
+		ID:           d.id,
+		Name:         d.name,
+		Brand:        d.brand,
+		SerialNumber: d.serialNumber,
+		State:        d.state,
+		CreationTime: d.creation_time,
+		Attributes:   d.Attributes(),
+		Tags:         d.Tags(),
+	}
+}
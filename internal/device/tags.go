@@ -0,0 +1,86 @@
+package device
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxTags caps how many tags a device may carry.
+const MaxTags = 32
+
+var tagRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+
+// NormalizeTag lower-cases and trims a tag and checks its shape. Tags group
+// devices by project, location and the like ("project:atlas", "berlin-3").
+func NormalizeTag(tag string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(tag))
+	if t == "" {
+		return "", ErrRequired("tag")
+	}
+	if !tagRe.MatchString(t) {
+		return "", ErrInvalid("tag", `tag "`+tag+`" must be 1-64 characters of a-z, 0-9, "_", ".", ":" or "-"`, http.StatusBadRequest)
+	}
+	return t, nil
+}
+
+// Tags returns the device's tags in sorted order.
+func (d *Device) Tags() []string {
+	out := make([]string, 0, len(d.tags))
+	for t := range d.tags {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// HasTag reports whether the device carries tag, compared after normalization.
+func (d *Device) HasTag(tag string) bool {
+	t, err := NormalizeTag(tag)
+	if err != nil {
+		return false
+	}
+	_, ok := d.tags[t]
+	return ok
+}
+
+// AddTag adds a tag; adding one the device already has is a no-op.
+func (d *Device) AddTag(tag string) error {
+	t, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.tags[t]; ok {
+		return nil
+	}
+	if len(d.tags) >= MaxTags {
+		return ErrInvalid("tags", "a device may carry at most 32 tags", http.StatusBadRequest)
+	}
+	if d.tags == nil {
+		d.tags = map[string]struct{}{}
+	}
+	d.tags[t] = struct{}{}
+	return nil
+}
+
+// RemoveTag removes a tag; removing one the device lacks is a no-op.
+func (d *Device) RemoveTag(tag string) error {
+	t, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	delete(d.tags, t)
+	return nil
+}
+
+// SetTags replaces every tag, normalizing and de-duplicating them.
+func (d *Device) SetTags(tags []string) error {
+	d.tags = nil
+	for _, t := range tags {
+		if err := d.AddTag(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package device
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "Project:Atlas", want: "project:atlas"},
+		{in: "  berlin-3 ", want: "berlin-3"},
+		{in: "", wantErr: true},
+		{in: "-leading", wantErr: true},
+		{in: "has space", wantErr: true},
+		{in: "a,b", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeTag(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeTag(%q): expected error, got %q", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeTag(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestDeviceTags(t *testing.T) {
+	d, err := New("iPhone", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetTags([]string{"Berlin", "atlas", "berlin"}); err != nil {
+		t.Fatalf("SetTags: %v", err)
+	}
+	if got := d.Tags(); !reflect.DeepEqual(got, []string{"atlas", "berlin"}) {
+		t.Fatalf("Tags() = %v", got)
+	}
+	if err := d.RemoveTag("ATLAS"); err != nil || d.HasTag("atlas") {
+		t.Fatalf("RemoveTag: err=%v, still tagged=%v", err, d.HasTag("atlas"))
+	}
+
+	for i := len(d.Tags()); i < MaxTags; i++ {
+		if err := d.AddTag("t" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("AddTag #%d: %v", i, err)
+		}
+	}
+	if err := d.AddTag("one-too-many"); err == nil {
+		t.Fatal("expected error past MaxTags")
+	}
+	if err := d.AddTag("berlin"); err != nil {
+		t.Fatalf("re-adding an existing tag should be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package filter parses the list endpoint's filter expressions, e.g.
+//
+//	state in (available,in-use) and created_after=2025-01-01 and name~"Galaxy"
+//
+// into a small AST that storage backends compile into their own query
+// language. Every field and operator is checked against Fields while parsing,
+// so a parsed Expr is always safe to compile.
+package filter
+
+type Op string
+
+const (
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpContains Op = "~"
+	OpGt       Op = ">"
+	OpGe       Op = ">="
+	OpLt       Op = "<"
+	OpLe       Op = "<="
+	OpIn       Op = "in"
+)
+
+// Expr is a node of a parsed filter expression.
+type Expr interface{ expr() }
+
+// And matches when every operand matches.
+type And struct{ Exprs []Expr }
+
+// Or matches when any operand matches.
+type Or struct{ Exprs []Expr }
+
+// Not negates its operand.
+type Not struct{ Expr Expr }
+
+// Comparison tests one field. Values holds strings for text and attribute
+// fields and time.Time for time fields; only OpIn carries more than one value.
+type Comparison struct {
+	Field  string
+	Op     Op
+	Values []any
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+// In builds a membership comparison, e.g. for ?any_tag=a,b.
+func In(field string, values ...string) Expr {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	return Comparison{Field: field, Op: OpIn, Values: vs}
+}
+
+// Eq builds a comparison for callers that translate plain query parameters
+// (e.g. ?brand=Apple) into an Expr.
+func Eq(field, value string) Expr {
+	return Comparison{Field: field, Op: OpEq, Values: []any{value}}
+}
+
+// AllOf joins the non-nil expressions with And, returning nil when there are
+// none and the expression itself when there is only one.
+func AllOf(exprs ...Expr) Expr {
+	var out []Expr
+	for _, e := range exprs {
+		if e != nil {
+			out = append(out, e)
+		}
+	}
+	switch len(out) {
+	case 0:
+		return nil
+	case 1:
+		return out[0]
+	default:
+		return And{Exprs: out}
+	}
+}
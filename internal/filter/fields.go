@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindTime
+	// KindAttr is a custom device attribute addressed as "attr.<name>". Its
+	// values stay strings; backends decide how to compare them.
+	KindAttr
+	// KindTag tests membership in the device's tag set: "=" means the tag is
+	// present, "in" means any of the listed tags is.
+	KindTag
+	// KindBool takes true or false, e.g. "overdue = true" for checked-out
+	// devices past their due date.
+	KindBool
+)
+
+// AttrPrefix marks custom attribute fields, e.g. "attr.os_version".
+const AttrPrefix = "attr."
+
+// Field describes a filterable device attribute.
+type Field struct {
+	Kind Kind
+	Ops  []Op
+}
+
+var (
+	textOps = []Op{OpEq, OpNe, OpContains, OpIn}
+	enumOps = []Op{OpEq, OpNe, OpIn}
+	timeOps = []Op{OpEq, OpNe, OpGt, OpGe, OpLt, OpLe}
+	attrOps = []Op{OpEq, OpNe, OpContains, OpIn, OpGt, OpGe, OpLt, OpLe}
+	tagOps  = []Op{OpEq, OpNe, OpIn}
+	boolOps = []Op{OpEq, OpNe}
+)
+
+// Fields is the allowlist of filterable fields.
+var Fields = map[string]Field{
+	"id":            {Kind: KindText, Ops: enumOps},
+	"name":          {Kind: KindText, Ops: textOps},
+	"brand":         {Kind: KindText, Ops: textOps},
+	"state":         {Kind: KindText, Ops: enumOps},
+	"serial_number": {Kind: KindText, Ops: enumOps},
+	"creation_time": {Kind: KindTime, Ops: timeOps},
+	"tag":           {Kind: KindTag, Ops: tagOps},
+	"overdue":       {Kind: KindBool, Ops: boolOps},
+}
+
+// aliases are shorthand fields that only accept "=" and expand to a
+// comparison on a real field.
+var aliases = map[string]struct {
+	field string
+	op    Op
+}{
+	"created_after":  {field: "creation_time", op: OpGt},
+	"created_before": {field: "creation_time", op: OpLt},
+}
+
+// Lookup resolves a field name, including "attr.<name>" attribute fields.
+func Lookup(name string) (Field, bool) {
+	if key, ok := strings.CutPrefix(name, AttrPrefix); ok {
+		if !device.ValidAttributeKey(key) {
+			return Field{}, false
+		}
+		return Field{Kind: KindAttr, Ops: attrOps}, true
+	}
+	f, ok := Fields[name]
+	return f, ok
+}
+
+func (f Field) allows(op Op) bool {
+	for _, o := range f.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based character offset into the expression
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:+", r)
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", pos})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", pos})
+			i++
+		case r == ',':
+			toks = append(toks, token{tokComma, ",", pos})
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(rs) {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					b.WriteRune(rs[i+1])
+					i += 2
+					continue
+				}
+				if rs[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(rs[i])
+				i++
+			}
+			if !closed {
+				return nil, errAt(token{tokString, string(rs[pos-1:]), pos}, "unterminated string")
+			}
+			toks = append(toks, token{tokString, b.String(), pos})
+		case strings.ContainsRune("=!~<>", r):
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errAt(token{tokOp, op, pos}, "unknown operator")
+			}
+			toks = append(toks, token{tokOp, op, pos})
+			i += len([]rune(op))
+		case isWordRune(r):
+			start := i
+			for i < len(rs) && isWordRune(rs[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(rs[start:i]), pos})
+		default:
+			return nil, errAt(token{tokWord, string(r), pos}, "unexpected character")
+		}
+	}
+	return append(toks, token{tokEOF, "", len(rs) + 1}), nil
+}
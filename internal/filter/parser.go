@@ -0,0 +1,244 @@
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// MaxLength caps the size of a filter expression.
+const MaxLength = 1000
+
+// Parse turns a filter expression into an Expr. An empty expression yields a
+// nil Expr. Errors are *device.DomainError values naming the offending token.
+//
+// Grammar (keywords are case-insensitive):
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expr ")" | comparison
+//	comparison = field op value | field "in" "(" value { "," value } ")"
+//	op         = "=" | "!=" | "~" | ">" | ">=" | "<" | "<="
+//	value      = word | "quoted string"
+func Parse(src string) (Expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	if len(src) > MaxLength {
+		return nil, &device.DomainError{
+			Code: "invalid_filter", Field: "filter",
+			Message: fmt.Sprintf("filter must be at most %d characters", MaxLength),
+			HTTP:    http.StatusBadRequest,
+		}
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errAt(t, "unexpected token")
+	}
+	return e, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{left}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return Or{Exprs: exprs}, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{left}
+	for p.keyword("and") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return And{Exprs: exprs}, nil
+}
+
+func (p *parser) parseFactor() (Expr, error) {
+	if p.keyword("not") {
+		e, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, errAt(t, `expected ")"`)
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	ft := p.next()
+	if ft.kind != tokWord {
+		return nil, errAt(ft, "expected a field name")
+	}
+	name := strings.ToLower(ft.text)
+
+	var op Op
+	ot := p.next()
+	switch {
+	case ot.kind == tokOp:
+		op = Op(ot.text)
+	case ot.kind == tokWord && strings.EqualFold(ot.text, "in"):
+		op = OpIn
+	default:
+		return nil, errAt(ot, "expected an operator")
+	}
+
+	if alias, ok := aliases[name]; ok {
+		if op != OpEq {
+			return nil, errAt(ot, "unsupported operator for "+name)
+		}
+		name, op = alias.field, alias.op
+	}
+	field, ok := Lookup(name)
+	if !ok {
+		return nil, errAt(ft, "unknown field")
+	}
+	if !field.allows(op) {
+		return nil, errAt(ot, "unsupported operator for "+name)
+	}
+
+	var raw []token
+	if op == OpIn {
+		if t := p.next(); t.kind != tokLParen {
+			return nil, errAt(t, `expected "(" after in`)
+		}
+		for {
+			vt := p.next()
+			if vt.kind != tokWord && vt.kind != tokString {
+				return nil, errAt(vt, "expected a value")
+			}
+			raw = append(raw, vt)
+			t := p.next()
+			if t.kind == tokRParen {
+				break
+			}
+			if t.kind != tokComma {
+				return nil, errAt(t, `expected "," or ")"`)
+			}
+		}
+	} else {
+		vt := p.next()
+		if vt.kind != tokWord && vt.kind != tokString {
+			return nil, errAt(vt, "expected a value")
+		}
+		raw = append(raw, vt)
+	}
+
+	values := make([]any, 0, len(raw))
+	for _, vt := range raw {
+		v, err := convert(field, vt)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return Comparison{Field: name, Op: op, Values: values}, nil
+}
+
+func convert(f Field, t token) (any, error) {
+	if f.Kind == KindTag {
+		tag, err := device.NormalizeTag(t.text)
+		if err != nil {
+			return nil, errAt(t, "invalid tag")
+		}
+		return tag, nil
+	}
+	if f.Kind == KindBool {
+		b, err := strconv.ParseBool(t.text)
+		if err != nil {
+			return nil, errAt(t, "invalid boolean (want true or false)")
+		}
+		return b, nil
+	}
+	if f.Kind != KindTime {
+		return t.text, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if v, err := time.Parse(layout, t.text); err == nil {
+			return v.UTC(), nil
+		}
+	}
+	return nil, errAt(t, "invalid time (want YYYY-MM-DD or RFC 3339)")
+}
+
+func errAt(t token, reason string) *device.DomainError {
+	msg := fmt.Sprintf("%s: %q at position %d", reason, t.text, t.pos)
+	if t.kind == tokEOF {
+		msg = reason + " at end of filter"
+	}
+	return &device.DomainError{
+		Code:    "invalid_filter",
+		Field:   "filter",
+		Message: msg,
+		HTTP:    http.StatusBadRequest,
+	}
+}
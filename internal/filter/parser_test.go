@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+func TestParse(t *testing.T) {
+	jan1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		input   string
+		want    Expr
+		wantErr string // substring of the DomainError message
+	}{
+		{
+			name:  "empty expression matches everything",
+			input: "   ",
+			want:  nil,
+		},
+		{
+			name:  "single equality",
+			input: "brand=Apple",
+			want:  Comparison{Field: "brand", Op: OpEq, Values: []any{"Apple"}},
+		},
+		{
+			name:  "in list, alias and quoted contains joined by and",
+			input: `state in (available,in-use) and created_after=2025-01-01 and name~"Galaxy S"`,
+			want: And{Exprs: []Expr{
+				Comparison{Field: "state", Op: OpIn, Values: []any{"available", "in-use"}},
+				Comparison{Field: "creation_time", Op: OpGt, Values: []any{jan1}},
+				Comparison{Field: "name", Op: OpContains, Values: []any{"Galaxy S"}},
+			}},
+		},
+		{
+			name:  "and binds tighter than or; parentheses and not",
+			input: "brand=Apple or not (state=inactive AND name~x)",
+			want: Or{Exprs: []Expr{
+				Comparison{Field: "brand", Op: OpEq, Values: []any{"Apple"}},
+				Not{Expr: And{Exprs: []Expr{
+					Comparison{Field: "state", Op: OpEq, Values: []any{"inactive"}},
+					Comparison{Field: "name", Op: OpContains, Values: []any{"x"}},
+				}}},
+			}},
+		},
+		{
+			name:  "attribute fields",
+			input: "attr.os_version>=17 and attr.owner_team in (qa,ops)",
+			want: And{Exprs: []Expr{
+				Comparison{Field: "attr.os_version", Op: OpGe, Values: []any{"17"}},
+				Comparison{Field: "attr.owner_team", Op: OpIn, Values: []any{"qa", "ops"}},
+			}},
+		},
+		{
+			name:  "tag membership normalizes tags",
+			input: "tag=Berlin and tag in (project:atlas, qa)",
+			want: And{Exprs: []Expr{
+				Comparison{Field: "tag", Op: OpEq, Values: []any{"berlin"}},
+				Comparison{Field: "tag", Op: OpIn, Values: []any{"project:atlas", "qa"}},
+			}},
+		},
+		{
+			name:  "overdue takes a boolean",
+			input: "overdue=true",
+			want:  Comparison{Field: "overdue", Op: OpEq, Values: []any{true}},
+		},
+		{
+			name:    "rejects non-boolean overdue",
+			input:   "overdue=soon",
+			wantErr: `invalid boolean (want true or false): "soon"`,
+		},
+		{
+			name:    "rejects invalid attribute name",
+			input:   "attr.9lives=true",
+			wantErr: `unknown field: "attr.9lives"`,
+		},
+		{
+			name:    "rejects unknown field",
+			input:   "color=red",
+			wantErr: `unknown field: "color" at position 1`,
+		},
+		{
+			name:    "rejects operator not allowed for field",
+			input:   "state~use",
+			wantErr: `unsupported operator for state: "~" at position 6`,
+		},
+		{
+			name:    "rejects invalid time",
+			input:   "creation_time>=yesterday",
+			wantErr: `"yesterday"`,
+		},
+		{
+			name:    "rejects dangling operator",
+			input:   "brand=",
+			wantErr: "expected a value at end of filter",
+		},
+		{
+			name:    "rejects unterminated string",
+			input:   `name~"Galaxy`,
+			wantErr: "unterminated string",
+		},
+		{
+			name:    "rejects trailing garbage",
+			input:   "brand=Apple Samsung",
+			wantErr: `unexpected token: "Samsung"`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+
+			if tt.wantErr != "" {
+				de, ok := err.(*device.DomainError)
+				if !ok {
+					t.Fatalf("expected *DomainError, got %T: %v", err, err)
+				}
+				if de.Code != "invalid_filter" || de.Field != "filter" {
+					t.Fatalf("want invalid_filter/filter, got %s/%s", de.Code, de.Field)
+				}
+				if !strings.Contains(de.Message, tt.wantErr) {
+					t.Fatalf("want message containing %q, got %q", tt.wantErr, de.Message)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("want %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
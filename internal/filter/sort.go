@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// SortKey orders results by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Sortable is the allowlist of fields accepted by ParseSort.
+var Sortable = map[string]bool{
+	"id":            true,
+	"name":          true,
+	"brand":         true,
+	"state":         true,
+	"creation_time": true,
+}
+
+// ParseSort parses a comma-separated sort spec such as "name,-creation_time",
+// where a leading "-" sorts that field descending. An empty spec yields nil.
+func ParseSort(spec string) ([]SortKey, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		k := SortKey{Field: strings.ToLower(part)}
+		if strings.HasPrefix(k.Field, "-") {
+			k.Field, k.Desc = k.Field[1:], true
+		} else {
+			k.Field = strings.TrimPrefix(k.Field, "+")
+		}
+		if !Sortable[k.Field] {
+			return nil, errSort(fmt.Sprintf("unknown sort field %q", part))
+		}
+		if seen[k.Field] {
+			return nil, errSort(fmt.Sprintf("duplicate sort field %q", part))
+		}
+		seen[k.Field] = true
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// FormatSort is the inverse of ParseSort.
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func errSort(msg string) *device.DomainError {
+	return &device.DomainError{Code: "invalid_sort", Field: "sort", Message: msg, HTTP: http.StatusBadRequest}
+}
@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    []SortKey
+		wantErr bool
+	}{
+		{name: "empty spec", input: "", want: nil},
+		{
+			name:  "mixed directions",
+			input: "name, -creation_time",
+			want:  []SortKey{{Field: "name"}, {Field: "creation_time", Desc: true}},
+		},
+		{name: "rejects unknown field", input: "color", wantErr: true},
+		{name: "rejects duplicate field", input: "name,-name", wantErr: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("want %#v, got %#v", tt.want, got)
+			}
+			if tt.input != "" && FormatSort(got) != "name,-creation_time" {
+				t.Fatalf("FormatSort round-trip: got %q", FormatSort(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// listCosts names the fields that return lists of objects, the argument
+// that sizes them and the size assumed when it is absent. Their selections
+// count once per element.
+var listCosts = map[string]struct {
+	arg  string
+	size int
+}{
+	"devices":     {"first", defaultFirst},
+	"history":     {"last", defaultLast},
+	"assignments": {"last", defaultAssignments},
+}
+
+// complexity estimates the cost of the most expensive operation in query:
+// every selected field costs 1, and the selections under a list field count
+// once per element it may return. Fragments are expanded. A size given by a
+// variable is its value in variables, else its default in the operation's
+// variable definitions, else maxListSize. It returns ok false for documents
+// it cannot read.
+func complexity(query string, variables map[string]any) (cost int, ok bool) {
+	p := &costParser{lex: lexer{src: query}, vars: variables, fragments: map[string][]selection{}}
+	p.next()
+	var ops []operation
+	for p.tok != "" {
+		switch {
+		case p.tok == "{":
+			ops = append(ops, operation{sels: p.selectionSet()})
+		case p.tok == "fragment":
+			p.next()
+			name := p.tok
+			p.next() // name
+			p.next() // on
+			p.next() // type condition
+			p.directives()
+			p.fragments[name] = p.selectionSet()
+		case p.tok == "query" || p.tok == "mutation" || p.tok == "subscription":
+			p.next()
+			if isName(p.tok) {
+				p.next()
+			}
+			var op operation
+			if p.tok == "(" {
+				op.defaults = p.variableDefinitions()
+			}
+			p.directives()
+			op.sels = p.selectionSet()
+			ops = append(ops, op)
+		default:
+			return 0, false
+		}
+		if p.bad {
+			return 0, false
+		}
+	}
+	for _, op := range ops {
+		cost = max(cost, p.cost(op.sels, op.defaults, map[string]bool{}))
+	}
+	return cost, true
+}
+
+// operation is a query, mutation or subscription with the integer defaults
+// of its variables.
+type operation struct {
+	sels     []selection
+	defaults map[string]int
+}
+
+// selection is a field, a fragment spread (spread set) or the contents of an
+// inline fragment (name empty). A list field sized by a variable names it in
+// sizeVar; it is resolved per operation, since fragments are shared.
+type selection struct {
+	name     string
+	size     int
+	sizeVar  string
+	spread   string
+	children []selection
+}
+
+type costParser struct {
+	lex       lexer
+	tok       string
+	vars      map[string]any
+	fragments map[string][]selection
+	bad       bool
+}
+
+func (p *costParser) next() { p.tok = p.lex.next() }
+
+func (p *costParser) expect(tok string) {
+	if p.tok != tok {
+		p.bad = true
+	}
+	p.next()
+}
+
+func (p *costParser) selectionSet() []selection {
+	var out []selection
+	p.expect("{")
+	for p.tok != "}" && p.tok != "" && !p.bad {
+		out = append(out, p.selection())
+	}
+	p.expect("}")
+	return out
+}
+
+func (p *costParser) selection() selection {
+	if p.tok == "..." {
+		p.next()
+		if p.tok == "on" || p.tok == "@" || p.tok == "{" {
+			if p.tok == "on" {
+				p.next()
+				p.next()
+			}
+			p.directives()
+			return selection{children: p.selectionSet()}
+		}
+		name := p.tok
+		p.next()
+		p.directives()
+		return selection{spread: name}
+	}
+
+	if !isName(p.tok) {
+		p.bad = true
+		return selection{}
+	}
+	s := selection{name: p.tok}
+	p.next()
+	if p.tok == ":" { // alias
+		p.next()
+		s.name = p.tok
+		p.next()
+	}
+	lc, isList := listCosts[s.name]
+	s.size = 1
+	if isList {
+		s.size = lc.size
+	}
+	if p.tok == "(" {
+		args := p.arguments()
+		if v, ok := args[lc.arg]; isList && ok {
+			if name, isVar := strings.CutPrefix(v, "$"); isVar {
+				s.sizeVar = name
+			} else if n, err := strconv.Atoi(v); err == nil {
+				s.size = n
+			}
+		}
+	}
+	p.directives()
+	if p.tok == "{" {
+		s.children = p.selectionSet()
+	}
+	return s
+}
+
+// arguments reads an argument list and returns the scalar ones as written,
+// variables as "$name".
+func (p *costParser) arguments() map[string]string {
+	out := map[string]string{}
+	p.expect("(")
+	for p.tok != ")" && p.tok != "" && !p.bad {
+		name := p.tok
+		p.next()
+		p.expect(":")
+		if p.tok == "$" {
+			p.next()
+			out[name] = "$" + p.tok
+			p.next()
+			continue
+		}
+		if p.tok != "[" && p.tok != "{" {
+			out[name] = p.tok
+		}
+		p.value()
+	}
+	p.expect(")")
+	return out
+}
+
+// variableDefinitions reads an operation's variable definitions and returns
+// the integer defaults.
+func (p *costParser) variableDefinitions() map[string]int {
+	out := map[string]int{}
+	p.expect("(")
+	for p.tok != ")" && p.tok != "" && !p.bad {
+		p.expect("$")
+		name := p.tok
+		p.next()
+		p.expect(":")
+		for p.tok == "[" || p.tok == "]" || p.tok == "!" || isName(p.tok) {
+			p.next()
+		}
+		if p.tok == "=" {
+			p.next()
+			if n, err := strconv.Atoi(p.tok); err == nil {
+				out[name] = n
+			}
+			p.value()
+		}
+		p.directives()
+	}
+	p.expect(")")
+	return out
+}
+
+// value skips one value: a scalar, a variable, a list or an object.
+func (p *costParser) value() {
+	switch p.tok {
+	case "[":
+		p.skipBalanced("[", "]")
+	case "{":
+		p.skipBalanced("{", "}")
+	case "$":
+		p.next()
+		p.next()
+	default:
+		p.next()
+	}
+}
+
+func (p *costParser) directives() {
+	for p.tok == "@" {
+		p.next()
+		p.next()
+		if p.tok == "(" {
+			p.skipBalanced("(", ")")
+		}
+	}
+}
+
+func (p *costParser) skipBalanced(open, close string) {
+	depth := 0
+	for p.tok != "" {
+		switch p.tok {
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return
+		}
+	}
+	p.bad = true
+}
+
+func (p *costParser) cost(sels []selection, defaults map[string]int, visiting map[string]bool) int {
+	total := 0
+	for _, s := range sels {
+		switch {
+		case s.spread != "":
+			if visiting[s.spread] {
+				continue
+			}
+			visiting[s.spread] = true
+			total += p.cost(p.fragments[s.spread], defaults, visiting)
+			delete(visiting, s.spread)
+		case s.name == "":
+			total += p.cost(s.children, defaults, visiting)
+		default:
+			total += 1 + max(p.size(s, defaults), 0)*p.cost(s.children, defaults, visiting)
+		}
+	}
+	return total
+}
+
+// size is how many elements s may return.
+func (p *costParser) size(s selection, defaults map[string]int) int {
+	if s.sizeVar == "" {
+		return s.size
+	}
+	if v, ok := p.vars[s.sizeVar].(float64); ok {
+		return int(v)
+	}
+	if n, ok := defaults[s.sizeVar]; ok {
+		return n
+	}
+	return maxListSize
+}
+
+// lexer splits a GraphQL document into punctuators, names, numbers and
+// strings, dropping whitespace, commas and comments.
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() string {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == ',' || unicode.IsSpace(rune(c)):
+			l.pos++
+		default:
+			return l.token()
+		}
+	}
+	return ""
+}
+
+func (l *lexer) token() string {
+	start := l.pos
+	rest := l.src[l.pos:]
+	switch {
+	case strings.HasPrefix(rest, "..."):
+		l.pos += 3
+	case strings.HasPrefix(rest, `"""`):
+		end := strings.Index(rest[3:], `"""`)
+		if end < 0 {
+			l.pos = len(l.src)
+		} else {
+			l.pos += 3 + end + 3
+		}
+	case rest[0] == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' && l.src[l.pos] != '\n' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		l.pos++
+	case rest[0] == '-' || rest[0] >= '0' && rest[0] <= '9':
+		// Numbers, including fractions and exponents.
+		l.pos++
+		for l.pos < len(l.src) && (isNameByte(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+	case isNameByte(rest[0]):
+		l.pos++
+		for l.pos < len(l.src) && isNameByte(l.src[l.pos]) {
+			l.pos++
+		}
+	default:
+		l.pos++
+	}
+	return l.src[start:min(l.pos, len(l.src))]
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isName(tok string) bool {
+	return tok != "" && isNameByte(tok[0]) && (tok[0] < '0' || tok[0] > '9')
+}
@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// queryError is a resolver error as clients see it: the message plus the
+// REST error code (and field, if any) under extensions.
+type queryError struct {
+	code    string
+	field   string
+	message string
+}
+
+func (e *queryError) Error() string { return e.message }
+
+func (e *queryError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.code}
+	if e.field != "" {
+		ext["field"] = e.field
+	}
+	return ext
+}
+
+// toError translates use-case errors like writeJSONError does for REST;
+// unexpected errors are not echoed to the client.
+func toError(err error) error {
+	var derr *device.DomainError
+	var nf *repository.NotFoundError
+	switch {
+	case errors.As(err, &derr):
+		return &queryError{code: derr.Code, field: derr.Field, message: derr.Message}
+	case errors.As(err, &nf):
+		return &queryError{code: "not_found", field: "id", message: nf.Resource + " not found"}
+	case errors.Is(err, repository.ErrNotFound):
+		return &queryError{code: "not_found", message: "resource not found"}
+	case errors.Is(err, repository.ErrConflict):
+		return &queryError{code: "conflict", message: "the request conflicts with existing data"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &queryError{code: "timeout", message: "the request took too long"}
+	case errors.Is(err, context.Canceled):
+		return &queryError{code: "canceled", message: "the request was canceled"}
+	}
+	return &queryError{code: "internal_error", message: "unexpected error"}
+}
+
+// isNotFound reports whether err means the device does not exist, however
+// the service reported it.
+func isNotFound(err error) bool {
+	var derr *device.DomainError
+	if errors.As(err, &derr) {
+		return derr.Code == "not_found"
+	}
+	return errors.Is(err, repository.ErrNotFound)
+}
+
+func errUnsupported(message string) error {
+	return &queryError{code: "not_implemented", message: message}
+}
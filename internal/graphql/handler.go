@@ -0,0 +1,152 @@
+// Package graphql serves devices over GraphQL at /graphql: queries with
+// filtered, paginated device connections and their history and
+// assignments, mutations through internal/service, and a subscription for
+// changes. Like internal/http it only translates.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/service"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Query limits. maxDepth counts nested selection sets; see complexity for
+// how maxComplexity is counted.
+const (
+	maxDepth       = 8
+	maxComplexity  = 1000
+	maxQueryLength = 16 << 10
+	maxBodyBytes   = 64 << 10
+)
+
+// tenantHeader names the tenant whose attribute schema applies, as in the
+// REST API; it is not authenticated (see service.DefaultTenant).
+const tenantHeader = "X-Tenant-ID"
+
+type tenantKey struct{}
+
+// Handler serves GraphQL over HTTP. Queries and mutations are POSTed as
+// JSON and answered with JSON; a request that accepts text/event-stream is
+// run as a subscription and streamed as "next" events, ending with
+// "complete".
+type Handler struct {
+	schema *gql.Schema
+}
+
+func NewHandler(repo repository.DeviceRepository, bus *pubsub.Bus) *Handler {
+	root := &resolver{repo: repo, devices: service.NewDevices(repo), bus: bus}
+	schema := gql.MustParseSchema(schemaSDL, root,
+		gql.UseStringDescriptions(),
+		gql.MaxDepth(maxDepth),
+		gql.MaxQueryLength(maxQueryLength),
+	)
+	return &Handler{schema: schema}
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeErrors(w, http.StatusMethodNotAllowed, "use POST with a JSON body")
+		return
+	}
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeErrors(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	// A document the cost estimate cannot read is refused rather than run
+	// unmetered; the schema's own errors say what is wrong with it, if it
+	// finds anything.
+	cost, ok := complexity(req.Query, req.Variables)
+	if !ok {
+		if errs := h.schema.Validate(req.Query); len(errs) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(gql.Response{Errors: errs})
+			return
+		}
+		writeErrors(w, http.StatusOK, "query complexity could not be determined")
+		return
+	}
+	if cost > maxComplexity {
+		writeErrors(w, http.StatusOK, fmt.Sprintf("query complexity %d exceeds the limit of %d", cost, maxComplexity))
+		return
+	}
+
+	ctx := r.Context()
+	if t := strings.TrimSpace(r.Header.Get(tenantHeader)); t != "" {
+		ctx = context.WithValue(ctx, tenantKey{}, t)
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.stream(ctx, w, req)
+		return
+	}
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// stream runs a subscription and writes each result as a server-sent
+// event until the client goes away or the subscription ends.
+func (h *Handler) stream(ctx context.Context, w http.ResponseWriter, req request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrors(w, http.StatusNotImplemented, "streaming is not supported by this connection")
+		return
+	}
+	results, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for res := range results {
+		data, err := json.Marshal(res)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+	_, _ = fmt.Fprint(w, "event: complete\ndata:\n\n")
+	flusher.Flush()
+}
+
+func writeErrors(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(gql.Response{Errors: []*gqlerrors.QueryError{{Message: message}}})
+}
+
+// tenantID names the request's tenant, or "" for the default one.
+func tenantID(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
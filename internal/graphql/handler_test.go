@@ -0,0 +1,241 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/marcboeker/go-duckdb"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	duckdbrepo "github.com/leandronowras/device-api/internal/repository/duckdb"
+)
+
+type result struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func serve(t *testing.T) (*httptest.Server, *pubsub.Bus) {
+	t.Helper()
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	bus := pubsub.NewBus()
+	srv := httptest.NewServer(NewHandler(duckdbrepo.NewDeviceRepository(db), bus))
+	t.Cleanup(srv.Close)
+	return srv, bus
+}
+
+func post(t *testing.T, srv *httptest.Server, query string, vars map[string]any) result {
+	t.Helper()
+	body, _ := json.Marshal(request{Query: query, Variables: vars})
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestMutationsAndQueries(t *testing.T) {
+	srv, _ := serve(t)
+
+	res := post(t, srv, `mutation($in: CreateDeviceInput!) { createDevice(input: $in) { id state attributes tags } }`,
+		map[string]any{"in": map[string]any{"name": "Phone", "brand": "Acme", "attributes": map[string]any{"ram_gb": 8}, "tags": []any{"lab"}}})
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	created := res.Data["createDevice"].(map[string]any)
+	id := created["id"].(string)
+	if created["state"] != device.StateAvailable || created["attributes"].(map[string]any)["ram_gb"] != float64(8) {
+		t.Errorf("created = %v", created)
+	}
+
+	res = post(t, srv, `mutation($id: ID!) { updateDevice(id: $id, input: {name: "Tablet", attributes: {ram_gb: null}}) { name attributes } }`,
+		map[string]any{"id": id})
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	if got := res.Data["updateDevice"].(map[string]any); got["name"] != "Tablet" || len(got["attributes"].(map[string]any)) != 0 {
+		t.Errorf("updated = %v", got)
+	}
+
+	res = post(t, srv, `query($id: ID!) { device(id: $id) { name history { type device { name } } assignments { id } } missing: device(id: "nope") { id } }`,
+		map[string]any{"id": id})
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	got := res.Data["device"].(map[string]any)
+	history := got["history"].([]any)
+	if len(history) != 2 || history[0].(map[string]any)["type"] != "updated" {
+		t.Errorf("history = %v", history)
+	}
+	if res.Data["missing"] != nil {
+		t.Errorf("missing = %v, want null", res.Data["missing"])
+	}
+}
+
+func TestDevicesConnectionPages(t *testing.T) {
+	srv, _ := serve(t)
+	for _, name := range []string{"A", "B", "C"} {
+		res := post(t, srv, `mutation($n: String!) { createDevice(input: {name: $n, brand: "Acme", tags: ["lab"]}) { id } }`, map[string]any{"n": name})
+		if len(res.Errors) > 0 {
+			t.Fatal(res.Errors)
+		}
+	}
+
+	const q = `query($after: String) {
+		devices(filter: {brand: "Acme", tags: ["lab"]}, sort: "name", first: 2, after: $after) {
+			nodes { name }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	var names []string
+	var after any
+	for range 3 {
+		res := post(t, srv, q, map[string]any{"after": after})
+		if len(res.Errors) > 0 {
+			t.Fatal(res.Errors)
+		}
+		conn := res.Data["devices"].(map[string]any)
+		for _, n := range conn["nodes"].([]any) {
+			names = append(names, n.(map[string]any)["name"].(string))
+		}
+		info := conn["pageInfo"].(map[string]any)
+		if info["hasNextPage"] != true {
+			break
+		}
+		after = info["endCursor"]
+	}
+	if strings.Join(names, ",") != "A,B,C" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestDomainErrorsCarryCodeAndField(t *testing.T) {
+	srv, _ := serve(t)
+	res := post(t, srv, `mutation { createDevice(input: {name: "Phone", brand: "Acme", state: "broken"}) { id } }`, nil)
+	if len(res.Errors) != 1 {
+		t.Fatalf("errors = %v", res.Errors)
+	}
+	if ext := res.Errors[0].Extensions; ext["code"] != "invalid_state" || ext["field"] != "state" {
+		t.Errorf("extensions = %v", ext)
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	srv, _ := serve(t)
+
+	res := post(t, srv, `{ devices(first: 100) { nodes { history(last: 100) { device { id name } } } } }`, nil)
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "complexity") {
+		t.Errorf("complex query: errors = %v", res.Errors)
+	}
+	res = post(t, srv, `query($n: Int = 100) { devices(first: $n) { nodes { history(last: $n) { device { id } } } } }`, nil)
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "complexity") {
+		t.Errorf("complex query through variable defaults: errors = %v", res.Errors)
+	}
+	res = post(t, srv, `{ devices { nodes { id }`, nil)
+	if len(res.Errors) == 0 || res.Data != nil {
+		t.Errorf("unreadable query: data = %v, errors = %v", res.Data, res.Errors)
+	}
+
+	deep := `{ device(id: "x") { history { device { history { device { history { device { history { device { id } } } } } } } } } }`
+	res = post(t, srv, deep, nil)
+	if len(res.Errors) == 0 {
+		t.Error("deep query was accepted")
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	tests := []struct {
+		query string
+		vars  map[string]any
+		want  int
+	}{
+		{`{ device(id: "a") { id name } }`, nil, 3},
+		{`{ devices { nodes { id } } }`, nil, 1 + 10*2},
+		{`query($n: Int) { devices(first: $n) { nodes { id } } }`, map[string]any{"n": float64(3)}, 1 + 3*2},
+		{`{ devices(first: 2) { nodes { ...F } } } fragment F on Device { id history(last: 5) { type } }`, nil, 1 + 2*(1+1+1+5*1)},
+		{`{ device(id: "a") { ... on Device { id } } }`, nil, 2},
+		// Variable sizes: the value sent, else the default, else the maximum.
+		{`query($n: Int = 100) { devices(first: $n) { nodes { history(last: $n) { type } } } }`, nil, 1 + 100*(1+1+100*1)},
+		{`query($n: Int = 100) { devices(first: $n) { nodes { history(last: $n) { type } } } }`, map[string]any{"n": float64(2)}, 1 + 2*(1+1+2*1)},
+		{`query($n: Int) { devices(first: $n) { nodes { id } } }`, nil, 1 + 100*2},
+		{`fragment F on Device { history(last: $n) { type } } query($n: Int! = 3) { devices(first: 1) { nodes { ...F } } }`, nil, 1 + 1*(1+1+3*1)},
+		{`{ device(id: "a") { assignments { id } } }`, nil, 1 + 1 + 10*1},
+		{`{ device(id: "a") { assignments(last: 2) { id } } }`, nil, 1 + 1 + 2*1},
+	}
+	for _, tt := range tests {
+		got, ok := complexity(tt.query, tt.vars)
+		if !ok || got != tt.want {
+			t.Errorf("complexity(%q) = %d, %v; want %d", tt.query, got, ok, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionStreamsChanges(t *testing.T) {
+	srv, bus := serve(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body, _ := json.Marshal(request{Query: `subscription { deviceChanged(brand: "acme") { type deviceId device { brand } } }`})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, bytes.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The subscription starts once the response headers are out; keep
+	// publishing until the matching event makes it through.
+	go func() {
+		tick := time.NewTicker(10 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			bus.Publish(pubsub.Message{ID: "1", Type: "created", DeviceID: "a", Device: device.Snapshot{ID: "a", Brand: "Other"}})
+			bus.Publish(pubsub.Message{ID: "2", Type: "created", DeviceID: "b", Device: device.Snapshot{ID: "b", Brand: "Acme"}})
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var res result
+		if err := json.Unmarshal([]byte(data), &res); err != nil {
+			t.Fatal(err)
+		}
+		ev := res.Data["deviceChanged"].(map[string]any)
+		if ev["deviceId"] != "b" || ev["device"].(map[string]any)["brand"] != "Acme" {
+			t.Errorf("event = %v", ev)
+		}
+		return
+	}
+	t.Fatalf("stream ended: %v", sc.Err())
+}
@@ -0,0 +1,292 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/service"
+)
+
+// Page sizes of the list fields; the defaults repeat the schema's. No list
+// argument may ask for more than maxListSize.
+const (
+	defaultFirst       = 10
+	defaultLast        = 20
+	defaultAssignments = 10
+	maxListSize        = 100
+)
+
+// maxSearchLen caps the free-text q filter.
+const maxSearchLen = 200
+
+// resolver is the root of the schema: its methods resolve the fields of
+// Query, Mutation and Subscription.
+type resolver struct {
+	repo    repository.DeviceRepository
+	devices *service.Devices
+	bus     *pubsub.Bus
+}
+
+// --- QUERIES -----------------------------------------------------------------
+
+func (r *resolver) Device(ctx context.Context, args struct{ ID gql.ID }) (*deviceResolver, error) {
+	d, err := r.devices.GetDevice(ctx, string(args.ID), nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, toError(err)
+	}
+	return r.device(d.Snapshot()), nil
+}
+
+type deviceFilter struct {
+	Brand      *string
+	State      *string
+	Tags       *[]string
+	Q          *string
+	Expression *string
+}
+
+func (r *resolver) Devices(ctx context.Context, args struct {
+	Filter *deviceFilter
+	Sort   *string
+	First  int32
+	After  *string
+}) (*connectionResolver, error) {
+	opts, err := listOptions(args.Filter, args.Sort)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if args.First < 1 || args.First > service.MaxPageSize {
+		return nil, toError(device.ErrInvalid("first", "first must be between 1 and 100", http.StatusBadRequest))
+	}
+	in := service.ListDevicesInput{Options: opts, Page: 1, Limit: int(args.First)}
+	if args.After != nil {
+		in.Cursor = *args.After
+	}
+	out, err := r.devices.ListDevices(ctx, in)
+	if err != nil {
+		return nil, toError(err)
+	}
+	conn := &connectionResolver{endCursor: out.NextCursor, hasNext: out.NextPage > 0 || out.NextCursor != ""}
+	for _, d := range out.Devices {
+		conn.nodes = append(conn.nodes, r.device(d.Snapshot()))
+	}
+	return conn, nil
+}
+
+// listOptions builds the list query the same way as the REST list
+// parameters of the same names.
+func listOptions(f *deviceFilter, sort *string) (repository.ListOptions, error) {
+	var opts repository.ListOptions
+	if sort != nil {
+		keys, err := filter.ParseSort(*sort)
+		if err != nil {
+			return opts, err
+		}
+		opts.Sort = keys
+	}
+	if f == nil {
+		return opts, nil
+	}
+
+	var terms []filter.Expr
+	if f.Expression != nil {
+		expr, err := filter.Parse(*f.Expression)
+		if err != nil {
+			return opts, err
+		}
+		terms = append(terms, expr)
+	}
+	if f.Brand != nil && strings.TrimSpace(*f.Brand) != "" {
+		terms = append(terms, filter.Eq("brand", strings.TrimSpace(*f.Brand)))
+	}
+	if f.State != nil && strings.TrimSpace(*f.State) != "" {
+		terms = append(terms, filter.Eq("state", strings.TrimSpace(*f.State)))
+	}
+	if f.Tags != nil {
+		for _, raw := range *f.Tags {
+			t, err := device.NormalizeTag(raw)
+			if err != nil {
+				return opts, err
+			}
+			terms = append(terms, filter.Eq("tag", t))
+		}
+	}
+	if f.Q != nil {
+		q := strings.TrimSpace(*f.Q)
+		if len(q) > maxSearchLen {
+			return opts, device.ErrInvalid("q", "q must be at most 200 characters", http.StatusBadRequest)
+		}
+		opts.Search = q
+	}
+	opts.Filter = filter.AllOf(terms...)
+	return opts, nil
+}
+
+// --- MUTATIONS ---------------------------------------------------------------
+
+type createDeviceInput struct {
+	Name         string
+	Brand        string
+	State        *string
+	SerialNumber *string
+	Attributes   *jsonValue
+	Tags         *[]string
+}
+
+func (r *resolver) CreateDevice(ctx context.Context, args struct{ Input createDeviceInput }) (*deviceResolver, error) {
+	in := service.CreateDeviceInput{
+		Tenant:       tenantID(ctx),
+		Name:         args.Input.Name,
+		Brand:        args.Input.Brand,
+		State:        deref(args.Input.State),
+		SerialNumber: deref(args.Input.SerialNumber),
+	}
+	attrs, err := args.Input.Attributes.object()
+	if err != nil {
+		return nil, toError(err)
+	}
+	in.Attributes = attrs
+	if args.Input.Tags != nil {
+		in.Tags = *args.Input.Tags
+	}
+	d, err := r.devices.CreateDevice(ctx, in)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.device(d.Snapshot()), nil
+}
+
+type updateDeviceInput struct {
+	Name         *string
+	Brand        *string
+	State        *string
+	SerialNumber *string
+	Attributes   *jsonValue
+	Tags         *[]string
+}
+
+func (r *resolver) UpdateDevice(ctx context.Context, args struct {
+	ID    gql.ID
+	Input updateDeviceInput
+}) (*deviceResolver, error) {
+	attrs, err := args.Input.Attributes.object()
+	if err != nil {
+		return nil, toError(err)
+	}
+	d, err := r.devices.UpdateDevice(ctx, service.UpdateDeviceInput{
+		Tenant:       tenantID(ctx),
+		ID:           string(args.ID),
+		Name:         args.Input.Name,
+		Brand:        args.Input.Brand,
+		State:        args.Input.State,
+		SerialNumber: args.Input.SerialNumber,
+		Attributes:   attrs,
+		Tags:         args.Input.Tags,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.device(d.Snapshot()), nil
+}
+
+func (r *resolver) DeleteDevice(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	if err := r.devices.DeleteDevice(ctx, string(args.ID)); err != nil {
+		return "", toError(err)
+	}
+	return args.ID, nil
+}
+
+// --- SUBSCRIPTIONS -----------------------------------------------------------
+
+// DeviceChanged relays bus messages matching the arguments until the client
+// goes away. Like the WebSocket endpoint, a subscriber that falls behind is
+// dropped by the bus, which ends the subscription.
+func (r *resolver) DeviceChanged(ctx context.Context, args struct {
+	IDs   *[]gql.ID
+	Brand *string
+	State *string
+}) (<-chan *eventResolver, error) {
+	ids := map[string]bool{}
+	if args.IDs != nil {
+		for _, id := range *args.IDs {
+			ids[string(id)] = true
+		}
+	}
+	brand, state := strings.TrimSpace(deref(args.Brand)), strings.TrimSpace(deref(args.State))
+
+	sub := r.bus.Subscribe(pubsub.DefaultBuffer)
+	out := make(chan *eventResolver)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if len(ids) > 0 && !ids[m.DeviceID] {
+					continue
+				}
+				if brand != "" && !strings.EqualFold(m.Device.Brand, brand) {
+					continue
+				}
+				if state != "" && !strings.EqualFold(m.Device.State, state) {
+					continue
+				}
+				select {
+				case out <- &eventResolver{m: m, device: r.device(m.Device)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// jsonValue is the JSON scalar.
+type jsonValue struct {
+	v any
+}
+
+func (jsonValue) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+func (j *jsonValue) UnmarshalGraphQL(input any) error {
+	j.v = input
+	return nil
+}
+
+func (j jsonValue) MarshalJSON() ([]byte, error) { return json.Marshal(j.v) }
+
+// object returns the value as attributes, or nil when it was not given.
+func (j *jsonValue) object() (map[string]any, error) {
+	if j == nil || j.v == nil {
+		return nil, nil
+	}
+	m, ok := j.v.(map[string]any)
+	if !ok {
+		return nil, device.ErrInvalid("attributes", "attributes must be an object", http.StatusBadRequest)
+	}
+	return m, nil
+}
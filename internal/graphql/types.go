@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// deviceResolver resolves Device from a snapshot, so current devices, log
+// entries and events of deleted devices all resolve the same way.
+type deviceResolver struct {
+	s device.Snapshot
+	r *resolver
+}
+
+func (r *resolver) device(s device.Snapshot) *deviceResolver {
+	return &deviceResolver{s: s, r: r}
+}
+
+func (d *deviceResolver) ID() gql.ID             { return gql.ID(d.s.ID) }
+func (d *deviceResolver) Name() string           { return d.s.Name }
+func (d *deviceResolver) Brand() string          { return d.s.Brand }
+func (d *deviceResolver) State() string          { return d.s.State }
+func (d *deviceResolver) CreationTime() gql.Time { return gql.Time{Time: d.s.CreationTime} }
+func (d *deviceResolver) Attributes() jsonValue  { return jsonValue{v: map[string]any(d.s.Attributes)} }
+func (d *deviceResolver) Tags() []string         { return append([]string{}, d.s.Tags...) }
+func (d *deviceResolver) SerialNumber() *string {
+	if d.s.SerialNumber == "" {
+		return nil
+	}
+	return &d.s.SerialNumber
+}
+
+func (d *deviceResolver) Assignments(ctx context.Context, args struct{ Last int32 }) ([]*assignmentResolver, error) {
+	store, ok := d.r.repo.(repository.AssignmentStore)
+	if !ok {
+		return nil, errUnsupported("assignments are not supported by this storage backend")
+	}
+	last := int(args.Last)
+	if last < 1 || last > maxListSize {
+		return nil, toError(device.ErrInvalid("last", "last must be between 1 and 100", http.StatusBadRequest))
+	}
+	list, err := store.Assignments(ctx, d.s.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	list = list[:min(last, len(list))]
+	out := make([]*assignmentResolver, 0, len(list))
+	for _, a := range list {
+		out = append(out, &assignmentResolver{a: a})
+	}
+	return out, nil
+}
+
+func (d *deviceResolver) History(ctx context.Context, args struct{ Last int32 }) ([]*changeResolver, error) {
+	log, ok := d.r.repo.(repository.EventLog)
+	if !ok {
+		return nil, errUnsupported("device history is not supported by this storage backend")
+	}
+	last := int(args.Last)
+	if last < 1 || last > maxListSize {
+		return nil, toError(device.ErrInvalid("last", "last must be between 1 and 100", http.StatusBadRequest))
+	}
+	events, err := log.DeviceHistory(ctx, d.s.ID, last)
+	if err != nil {
+		return nil, toError(err)
+	}
+	out := make([]*changeResolver, 0, len(events))
+	for _, e := range events {
+		var snap device.Snapshot
+		if err := json.Unmarshal(e.Payload, &snap); err != nil {
+			return nil, toError(err)
+		}
+		out = append(out, &changeResolver{e: e, device: d.r.device(snap)})
+	}
+	return out, nil
+}
+
+type connectionResolver struct {
+	nodes     []*deviceResolver
+	hasNext   bool
+	endCursor string
+}
+
+func (c *connectionResolver) Nodes() []*deviceResolver {
+	return append([]*deviceResolver{}, c.nodes...)
+}
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver { return &pageInfoResolver{c: c} }
+
+type pageInfoResolver struct {
+	c *connectionResolver
+}
+
+func (p *pageInfoResolver) HasNextPage() bool { return p.c.hasNext }
+
+func (p *pageInfoResolver) EndCursor() *string {
+	if p.c.endCursor == "" {
+		return nil
+	}
+	return &p.c.endCursor
+}
+
+type assignmentResolver struct {
+	a *device.Assignment
+}
+
+func (a *assignmentResolver) ID() gql.ID             { return gql.ID(a.a.ID) }
+func (a *assignmentResolver) Assignee() string       { return a.a.Assignee }
+func (a *assignmentResolver) CheckedOutAt() gql.Time { return gql.Time{Time: a.a.CheckedOutAt} }
+func (a *assignmentResolver) DueAt() gql.Time        { return gql.Time{Time: a.a.DueAt} }
+func (a *assignmentResolver) Overdue() bool          { return a.a.Overdue(time.Now()) }
+func (a *assignmentResolver) CheckedInAt() *gql.Time {
+	if a.a.CheckedInAt == nil {
+		return nil
+	}
+	return &gql.Time{Time: *a.a.CheckedInAt}
+}
+
+type changeResolver struct {
+	e      repository.Event
+	device *deviceResolver
+}
+
+func (c *changeResolver) ID() gql.ID              { return gql.ID(strconv.FormatInt(c.e.Seq, 10)) }
+func (c *changeResolver) Type() string            { return c.e.Type }
+func (c *changeResolver) OccurredAt() gql.Time    { return gql.Time{Time: c.e.OccurredAt} }
+func (c *changeResolver) Device() *deviceResolver { return c.device }
+
+type eventResolver struct {
+	m      pubsub.Message
+	device *deviceResolver
+}
+
+func (e *eventResolver) ID() gql.ID              { return gql.ID(e.m.ID) }
+func (e *eventResolver) Type() string            { return e.m.Type }
+func (e *eventResolver) DeviceID() gql.ID        { return gql.ID(e.m.DeviceID) }
+func (e *eventResolver) Device() *deviceResolver { return e.device }
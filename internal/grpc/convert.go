@@ -0,0 +1,30 @@
+package grpc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/grpc/devicev1"
+)
+
+// toProto converts the device representation shared with REST and the
+// event payloads.
+func toProto(s device.Snapshot) (*devicev1.Device, error) {
+	attrs, err := structpb.NewStruct(s.Attributes.JSONValues())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encode attributes: %v", err)
+	}
+	return &devicev1.Device{
+		Id:           s.ID,
+		Name:         s.Name,
+		Brand:        s.Brand,
+		SerialNumber: s.SerialNumber,
+		State:        s.State,
+		CreationTime: timestamppb.New(s.CreationTime),
+		Attributes:   attrs,
+		Tags:         s.Tags,
+	}, nil
+}
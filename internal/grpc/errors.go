@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// errorDomain is the google.rpc.ErrorInfo domain of the service's errors.
+const errorDomain = "device-api"
+
+// toStatus turns use-case errors into gRPC statuses. A DomainError keeps its
+// code as the ErrorInfo reason and, when it concerns one field, adds a
+// BadRequest field violation; storage and context errors map to their
+// closest codes, and anything else is INTERNAL without details.
+func toStatus(err error) error {
+	var derr *device.DomainError
+	switch {
+	case errors.As(err, &derr):
+		st := status.New(domainCode(derr), derr.Message)
+		details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: derr.Code, Domain: errorDomain}}
+		if derr.Field != "" {
+			details = append(details, &errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+					Field: derr.Field, Description: derr.Message, Reason: derr.Code,
+				}},
+			})
+		}
+		if withDetails, err := st.WithDetails(details...); err == nil {
+			st = withDetails
+		}
+		return st.Err()
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.Aborted, "the request conflicts with existing data")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, "unexpected error")
+}
+
+// domainCode maps the HTTP status a DomainError suggests to a gRPC code.
+func domainCode(e *device.DomainError) codes.Code {
+	switch e.HTTP {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		// Duplicates are ALREADY_EXISTS; everything else is a rule such as
+		// "in use" that the device's current state does not allow.
+		if e.Code == "conflict_serial_number" {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+	return codes.Internal
+}
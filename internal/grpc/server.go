@@ -0,0 +1,211 @@
+// Package grpc serves the device API over gRPC. Like internal/http it only
+// translates: the use cases live in internal/service.
+package grpc
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/leandronowras/device-api --go-grpc_out=../.. --go-grpc_opt=module=github.com/leandronowras/device-api device/v1/device.proto
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/grpc/devicev1"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/service"
+)
+
+// tenantKey is the metadata key naming the tenant whose attribute schema
+// applies, like the X-Tenant-ID header of the REST API.
+const tenantKey = "x-tenant-id"
+
+// maxSearchLen caps the free-text q field of ListDevices.
+const maxSearchLen = 200
+
+// Server implements devicev1.DeviceServiceServer.
+type Server struct {
+	devicev1.UnimplementedDeviceServiceServer
+
+	devices *service.Devices
+	// bus feeds WatchDevices; the outbox relay publishes to it.
+	bus *pubsub.Bus
+}
+
+func NewServer(repo repository.DeviceRepository, bus *pubsub.Bus) *Server {
+	return &Server{devices: service.NewDevices(repo), bus: bus}
+}
+
+var _ devicev1.DeviceServiceServer = (*Server)(nil)
+
+func (s *Server) CreateDevice(ctx context.Context, req *devicev1.CreateDeviceRequest) (*devicev1.Device, error) {
+	d, err := s.devices.CreateDevice(ctx, service.CreateDeviceInput{
+		Tenant:       tenantID(ctx),
+		Name:         req.GetName(),
+		Brand:        req.GetBrand(),
+		State:        req.GetState(),
+		SerialNumber: req.GetSerialNumber(),
+		Attributes:   req.GetAttributes().AsMap(),
+		Tags:         req.GetTags(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(d.Snapshot())
+}
+
+func (s *Server) GetDevice(ctx context.Context, req *devicev1.GetDeviceRequest) (*devicev1.Device, error) {
+	d, err := s.devices.GetDevice(ctx, req.GetId(), nil)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(d.Snapshot())
+}
+
+func (s *Server) ListDevices(ctx context.Context, req *devicev1.ListDevicesRequest) (*devicev1.ListDevicesResponse, error) {
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out, err := s.devices.ListDevices(ctx, service.ListDevicesInput{
+		Options: opts,
+		Page:    int(req.GetPage()),
+		Limit:   int(req.GetLimit()),
+		Cursor:  req.GetCursor(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &devicev1.ListDevicesResponse{
+		NextPage:     int32(out.NextPage),
+		PreviousPage: int32(out.PreviousPage),
+		NextCursor:   out.NextCursor,
+	}
+	for _, d := range out.Devices {
+		pb, err := toProto(d.Snapshot())
+		if err != nil {
+			return nil, err
+		}
+		resp.Devices = append(resp.Devices, pb)
+	}
+	return resp, nil
+}
+
+// listOptions mirrors the REST list query: brand and state match exactly,
+// q searches, filter and sort use the REST syntax.
+func listOptions(req *devicev1.ListDevicesRequest) (repository.ListOptions, error) {
+	q := strings.TrimSpace(req.GetQ())
+	if len(q) > maxSearchLen {
+		return repository.ListOptions{}, device.ErrInvalid("q", "q must be at most 200 characters", http.StatusBadRequest)
+	}
+	expr, err := filter.Parse(req.GetFilter())
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+	terms := []filter.Expr{expr}
+	if brand := strings.TrimSpace(req.GetBrand()); brand != "" {
+		terms = append(terms, filter.Eq("brand", brand))
+	}
+	if state := strings.TrimSpace(req.GetState()); state != "" {
+		terms = append(terms, filter.Eq("state", state))
+	}
+	keys, err := filter.ParseSort(req.GetSort())
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+	return repository.ListOptions{Filter: filter.AllOf(terms...), Search: q, Sort: keys}, nil
+}
+
+func (s *Server) UpdateDevice(ctx context.Context, req *devicev1.UpdateDeviceRequest) (*devicev1.Device, error) {
+	in := service.UpdateDeviceInput{
+		Tenant:       tenantID(ctx),
+		ID:           req.GetId(),
+		Name:         req.Name,
+		Brand:        req.Brand,
+		State:        req.State,
+		SerialNumber: req.SerialNumber,
+	}
+	if req.Attributes != nil {
+		in.Attributes = req.Attributes.AsMap()
+	}
+	if req.Tags != nil {
+		tags := req.Tags.GetTags()
+		in.Tags = &tags
+	}
+	d, err := s.devices.UpdateDevice(ctx, in)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(d.Snapshot())
+}
+
+func (s *Server) DeleteDevice(ctx context.Context, req *devicev1.DeleteDeviceRequest) (*emptypb.Empty, error) {
+	if err := s.devices.DeleteDevice(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchDevices relays bus messages matching the request until the client
+// goes away. It shares the bus with the WebSocket endpoint, so a watcher
+// more than pubsub.DefaultBuffer events behind is cut off.
+func (s *Server) WatchDevices(req *devicev1.WatchDevicesRequest, stream devicev1.DeviceService_WatchDevicesServer) error {
+	sub := s.bus.Subscribe(pubsub.DefaultBuffer)
+	defer sub.Close()
+
+	ids := map[string]bool{}
+	for _, id := range req.GetDeviceIds() {
+		ids[id] = true
+	}
+	brand, state := strings.TrimSpace(req.GetBrand()), strings.TrimSpace(req.GetState())
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					return status.Error(codes.ResourceExhausted, "watcher fell too far behind; call again")
+				}
+				return nil
+			}
+			if len(ids) > 0 && !ids[m.DeviceID] {
+				continue
+			}
+			if brand != "" && !strings.EqualFold(m.Device.Brand, brand) {
+				continue
+			}
+			if state != "" && !strings.EqualFold(m.Device.State, state) {
+				continue
+			}
+			pb, err := toProto(m.Device)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(&devicev1.DeviceEvent{Id: m.ID, Type: m.Type, DeviceId: m.DeviceID, Device: pb}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// tenantID names the tenant from the call's metadata, or "" for the
+// default one.
+func tenantID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, t := range md.Get(tenantKey) {
+		if t = strings.TrimSpace(t); t != "" {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	_ "github.com/marcboeker/go-duckdb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/grpc/devicev1"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	duckdbrepo "github.com/leandronowras/device-api/internal/repository/duckdb"
+)
+
+func dial(t *testing.T) (devicev1.DeviceServiceClient, *pubsub.Bus) {
+	t.Helper()
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	bus := pubsub.NewBus()
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	devicev1.RegisterDeviceServiceServer(gs, NewServer(duckdbrepo.NewDeviceRepository(db), bus))
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return devicev1.NewDeviceServiceClient(conn), bus
+}
+
+func TestDeviceLifecycle(t *testing.T) {
+	client, _ := dial(t)
+	ctx := context.Background()
+
+	attrs, _ := structpb.NewStruct(map[string]any{"purchase_date": "2024-03-01", "os_version": "17.4"})
+	created, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "Phone", Brand: "Acme", Tags: []string{"lab"}, Attributes: attrs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetState() != device.StateAvailable || created.GetCreationTime() == nil {
+		t.Errorf("created = %v", created)
+	}
+	// Dates are stored as times and come back in RFC 3339.
+	if got := created.GetAttributes().GetFields()["purchase_date"].GetStringValue(); got != "2024-03-01T00:00:00Z" {
+		t.Errorf("purchase_date = %q", got)
+	}
+	got, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: created.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetAttributes().GetFields()["purchase_date"].GetStringValue() != "2024-03-01T00:00:00Z" {
+		t.Errorf("GetDevice attributes = %v", got.GetAttributes())
+	}
+
+	name := "Tablet"
+	updated, err := client.UpdateDevice(ctx, &devicev1.UpdateDeviceRequest{Id: created.GetId(), Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetName() != "Tablet" || len(updated.GetTags()) != 1 {
+		t.Errorf("updated = %v", updated)
+	}
+
+	list, err := client.ListDevices(ctx, &devicev1.ListDevicesRequest{Brand: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetDevices()) != 1 {
+		t.Errorf("listed %d devices, want 1", len(list.GetDevices()))
+	}
+
+	if _, err := client.DeleteDevice(ctx, &devicev1.DeleteDeviceRequest{Id: created.GetId()}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: created.GetId()})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetDevice after delete: got %v", err)
+	}
+}
+
+func TestDomainErrorsCarryFieldViolations(t *testing.T) {
+	client, _ := dial(t)
+	ctx := context.Background()
+
+	_, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Brand: "Acme"})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v, want InvalidArgument", st.Code())
+	}
+	var reason, field string
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.GetReason()
+		case *errdetails.BadRequest:
+			field = d.GetFieldViolations()[0].GetField()
+		}
+	}
+	if reason != "required" || field != "name" {
+		t.Errorf("reason %q, field %q; want required, name", reason, field)
+	}
+
+	inUse, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "Phone", Brand: "Acme", State: device.StateInUse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.DeleteDevice(ctx, &devicev1.DeleteDeviceRequest{Id: inUse.GetId()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("deleting a device in use: got %v", err)
+	}
+}
+
+func TestWatchDevicesFilters(t *testing.T) {
+	client, bus := dial(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchDevices(ctx, &devicev1.WatchDevicesRequest{Brand: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subscription exists once the server has the call; publish until
+	// the first matching event makes it through.
+	go func() {
+		tick := time.NewTicker(10 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			bus.Publish(pubsub.Message{ID: "1", Type: "created", DeviceID: "a", Device: device.Snapshot{ID: "a", Brand: "Other"}})
+			bus.Publish(pubsub.Message{ID: "2", Type: "created", DeviceID: "b", Device: device.Snapshot{ID: "b", Brand: "Acme"}})
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.GetDeviceId() != "b" || ev.GetType() != "created" || ev.GetDevice().GetBrand() != "Acme" {
+		t.Errorf("event = %v", ev)
+	}
+}
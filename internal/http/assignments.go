@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+type assignmentResponse struct {
+	ID           string     `json:"id"`
+	DeviceID     string     `json:"device_id"`
+	Assignee     string     `json:"assignee"`
+	CheckedOutAt time.Time  `json:"checked_out_at"`
+	DueAt        time.Time  `json:"due_at"`
+	CheckedInAt  *time.Time `json:"checked_in_at"`
+	Overdue      bool       `json:"overdue"`
+}
+
+func toAssignmentResp(a *device.Assignment, now time.Time) assignmentResponse {
+	return assignmentResponse{
+		ID:           a.ID,
+		DeviceID:     a.DeviceID,
+		Assignee:     a.Assignee,
+		CheckedOutAt: a.CheckedOutAt,
+		DueAt:        a.DueAt,
+		CheckedInAt:  a.CheckedInAt,
+		Overdue:      a.Overdue(now),
+	}
+}
+
+// --- CHECKOUT / CHECKIN (/v1/devices/{id}/checkout, /checkin) ----------------
+
+func (h *Handler) CheckoutDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Assignee string    `json:"assignee"`
+		DueAt    time.Time `json:"due_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_json", Message: "invalid JSON body (due_at must be RFC 3339)", HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	var a *device.Assignment
+	err := h.withAssignments(r.Context(), func(tx repository.DeviceRepository, store repository.AssignmentStore) error {
+		d, err := tx.FindByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if a, err = d.Checkout(req.Assignee, req.DueAt, now); err != nil {
+			return err
+		}
+		if _, err := tx.Update(r.Context(), d); err != nil {
+			return err
+		}
+		return store.SaveAssignment(r.Context(), a)
+	})
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toAssignmentResp(a, now))
+}
+
+func (h *Handler) CheckinDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+
+	now := time.Now().UTC()
+	var open *device.Assignment
+	err := h.withAssignments(r.Context(), func(tx repository.DeviceRepository, store repository.AssignmentStore) error {
+		d, err := tx.FindByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		open, err = store.OpenAssignment(r.Context(), id)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err := d.Checkin(open, now); err != nil {
+			return err
+		}
+		if _, err := tx.Update(r.Context(), d); err != nil {
+			return err
+		}
+		if open == nil {
+			return nil
+		}
+		return store.SaveAssignment(r.Context(), open)
+	})
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	if open == nil {
+		// In use without a checkout (e.g. set via PATCH): nothing to close.
+		w.WriteHeader(stdhttp.StatusNoContent)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toAssignmentResp(open, now))
+}
+
+// --- ASSIGNMENT HISTORY (/v1/devices/{id}/assignments) -----------------------
+
+func (h *Handler) ListAssignments(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.repo.(repository.AssignmentStore)
+	if !ok {
+		writeJSONError(w, errAssignmentsUnsupported())
+		return
+	}
+	if _, err := h.repo.FindByID(r.Context(), id); err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	list, err := store.Assignments(r.Context(), id)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	now := time.Now().UTC()
+	resp := []assignmentResponse{}
+	for _, a := range list {
+		resp = append(resp, toAssignmentResp(a, now))
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
+
+// withAssignments runs fn in a transaction whose repository also keeps
+// assignment history, so the state change and its record commit together.
+func (h *Handler) withAssignments(ctx context.Context, fn func(tx repository.DeviceRepository, store repository.AssignmentStore) error) error {
+	if _, ok := h.repo.(repository.AssignmentStore); !ok {
+		return errAssignmentsUnsupported()
+	}
+	return h.repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		store, ok := tx.(repository.AssignmentStore)
+		if !ok {
+			return errAssignmentsUnsupported()
+		}
+		return fn(tx, store)
+	})
+}
+
+func errAssignmentsUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "device checkout is not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/service"
+)
+
+const (
+	tenantHeader = "X-Tenant-ID"
+
+	maxSchemaBytes = 64 << 10
+)
+
+// tenantID names the tenant whose attribute schema applies to a request. The
+// header is not authenticated; see service.DefaultTenant.
+func tenantID(r *stdhttp.Request) string {
+	if t := strings.TrimSpace(r.Header.Get(tenantHeader)); t != "" {
+		return t
+	}
+	return service.DefaultTenant
+}
+
+// validateAttributes checks attrs, normalized from sent, against the request
+// tenant's schema, if the backend stores schemas and the tenant has one.
+func (h *Handler) validateAttributes(r *stdhttp.Request, attrs device.Attributes, sent map[string]any) error {
+	return h.devices.ValidateAttributes(r.Context(), tenantID(r), attrs, sent)
+}
+
+// attributeFilters turns attr.<name>=value query params into filter terms.
+func attributeFilters(r *stdhttp.Request) ([]filter.Expr, error) {
+	var out []filter.Expr
+	for key, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(key, filter.AttrPrefix)
+		if !ok {
+			continue
+		}
+		if !device.ValidAttributeKey(name) {
+			return nil, device.ErrInvalid("attributes", `attribute name "`+name+`" must match [a-z][a-z0-9_]*`, stdhttp.StatusBadRequest)
+		}
+		for _, v := range values {
+			out = append(out, filter.Eq(key, v))
+		}
+	}
+	return out, nil
+}
+
+// --- ATTRIBUTE SCHEMAS (/v1/tenants/{tenant}/attribute-schema) ---------------
+
+func (h *Handler) GetAttributeSchema(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.AttributeSchemaStore)
+	if !ok {
+		writeJSONError(w, errSchemasUnsupported())
+		return
+	}
+	raw, err := store.AttributeSchema(r.Context(), chi.URLParam(r, "tenant"))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_found", Field: "tenant", Message: "attribute schema not found", HTTP: stdhttp.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(stdhttp.StatusOK)
+	_, _ = w.Write(raw)
+}
+
+func (h *Handler) PutAttributeSchema(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.AttributeSchemaStore)
+	if !ok {
+		writeJSONError(w, errSchemasUnsupported())
+		return
+	}
+	raw, err := io.ReadAll(stdhttp.MaxBytesReader(w, r.Body, maxSchemaBytes))
+	if err != nil {
+		writeJSONError(w, device.ErrInvalid("schema", "schema body is too large or unreadable", stdhttp.StatusBadRequest))
+		return
+	}
+	if _, err := device.ParseAttributeSchema(raw); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if err := store.PutAttributeSchema(r.Context(), chi.URLParam(r, "tenant"), raw); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+func (h *Handler) DeleteAttributeSchema(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.AttributeSchemaStore)
+	if !ok {
+		writeJSONError(w, errSchemasUnsupported())
+		return
+	}
+	err := store.DeleteAttributeSchema(r.Context(), chi.URLParam(r, "tenant"))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_found", Field: "tenant", Message: "attribute schema not found", HTTP: stdhttp.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+func errSchemasUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "attribute schemas are not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
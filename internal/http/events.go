@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// How often the event stream polls the log, and how often it sends a comment
+// line so proxies keep an idle connection open.
+var (
+	eventPollInterval = 500 * time.Millisecond
+	eventHeartbeat    = 15 * time.Second
+)
+
+const eventBatch = 100
+
+// --- EVENT STREAM (/v1/devices/events) ----------------------------------------
+
+// DeviceEvents streams device changes as server-sent events. Each event's id
+// is its position in the persisted log, so a client that reconnects with
+// Last-Event-ID (or ?last_event_id=) resumes where it left off; without one
+// the stream starts with the next change. brand and state filter like the
+// list endpoint, matched against the device as of the change.
+func (h *Handler) DeviceEvents(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	log, ok := h.repo.(repository.EventLog)
+	if !ok {
+		writeJSONError(w, &device.DomainError{
+			Code:    "not_implemented",
+			Message: "device events are not supported by this storage backend",
+			HTTP:    stdhttp.StatusNotImplemented,
+		})
+		return
+	}
+	flusher, ok := w.(stdhttp.Flusher)
+	if !ok {
+		writeJSONError(w, &device.DomainError{
+			Code:    "not_implemented",
+			Message: "streaming is not supported by this connection",
+			HTTP:    stdhttp.StatusNotImplemented,
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	brand := strings.TrimSpace(query.Get("brand"))
+	state := strings.TrimSpace(query.Get("state"))
+
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = query.Get("last_event_id")
+	}
+	var after int64
+	if raw = strings.TrimSpace(raw); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			writeJSONError(w, device.ErrInvalid("last_event_id", "Last-Event-ID must be a non-negative event id", stdhttp.StatusBadRequest))
+			return
+		}
+		after = n
+	} else {
+		n, err := log.LastEventSeq(r.Context())
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		after = n
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(stdhttp.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(eventPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		// Drain everything committed since the last poll.
+		for {
+			events, err := log.EventsAfter(r.Context(), after, eventBatch)
+			if err != nil {
+				return
+			}
+			for _, e := range events {
+				after = e.Seq
+				if !eventMatches(e, brand, state) {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, e.Payload); err != nil {
+					return
+				}
+			}
+			if len(events) > 0 {
+				flusher.Flush()
+			}
+			if len(events) < eventBatch {
+				break
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+func eventMatches(e repository.Event, brand, state string) bool {
+	if brand == "" && state == "" {
+		return true
+	}
+	var s device.Snapshot
+	if err := json.Unmarshal(e.Payload, &s); err != nil {
+		return false
+	}
+	if brand != "" && !strings.EqualFold(s.Brand, brand) {
+		return false
+	}
+	return state == "" || strings.EqualFold(s.State, state)
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+const (
+	mediaCSV    = "text/csv"
+	mediaNDJSON = "application/x-ndjson"
+)
+
+// exportTypes maps each acceptable Accept media range to the response it
+// selects; "" is the regular JSON response.
+var exportTypes = map[string]string{
+	mediaCSV:           mediaCSV,
+	mediaNDJSON:        mediaNDJSON,
+	"application/json": "",
+	"application/*":    "",
+	"text/*":           mediaCSV,
+	"*/*":              "",
+}
+
+// negotiateExport returns the export media type requested in the Accept
+// header, or "" when the client wants the regular JSON response. The range
+// with the highest q wins, earlier ranges breaking ties; a header that
+// accepts none of them is a 406.
+func negotiateExport(r *stdhttp.Request) (string, error) {
+	header := strings.TrimSpace(r.Header.Get("Accept"))
+	if header == "" {
+		return "", nil
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		selected, ok := exportTypes[mt]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = selected, q
+		}
+	}
+	if bestQ == 0 {
+		return "", device.ErrInvalid("accept", "accept must allow one of: application/json, text/csv, application/x-ndjson", stdhttp.StatusNotAcceptable)
+	}
+	return best, nil
+}
+
+// rowWriter encodes one device per call in a streaming export format.
+type rowWriter interface {
+	writeHeader() error
+	writeRow(d *device.Device) error
+	flush() error
+}
+
+type csvRows struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func (c *csvRows) writeHeader() error { return c.w.Write(c.fields) }
+
+func (c *csvRows) writeRow(d *device.Device) error {
+	rec := make([]string, len(c.fields))
+	for i, f := range c.fields {
+		rec[i] = csvCell(fieldString(d, f))
+	}
+	return c.w.Write(rec)
+}
+
+// csvCell keeps spreadsheets from evaluating a cell as a formula by
+// prefixing values that start with a formula trigger with a quote.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func (c *csvRows) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonRows struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func (n *ndjsonRows) writeHeader() error              { return nil }
+func (n *ndjsonRows) writeRow(d *device.Device) error { return n.enc.Encode(shapeResp(d, n.fields)) }
+func (n *ndjsonRows) flush() error                    { return nil }
+
+// exportDevices streams the devices produced by source straight to the
+// client, limited to fields when given. The status line is only committed once the first row arrives (or
+// the query finishes empty), so query errors can still be reported as JSON.
+func exportDevices(w stdhttp.ResponseWriter, mediaType string, fields []string, source func(fn func(d *device.Device) error) error) {
+	var rw rowWriter
+	if mediaType == mediaCSV {
+		if len(fields) == 0 {
+			fields = responseFields
+		}
+		rw = &csvRows{w: csv.NewWriter(w), fields: fields}
+	} else {
+		rw = &ndjsonRows{enc: json.NewEncoder(w), fields: fields}
+	}
+	flusher, _ := w.(stdhttp.Flusher)
+
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", mediaType)
+		if mediaType == mediaCSV {
+			w.Header().Set("Content-Disposition", `attachment; filename="devices.csv"`)
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+		return rw.writeHeader()
+	}
+
+	n := 0
+	err := source(func(d *device.Device) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := rw.writeRow(d); err != nil {
+			return err
+		}
+		n++
+		if n%100 == 0 {
+			if err := rw.flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil && !started {
+		writeJSONError(w, err)
+		return
+	}
+	if !started {
+		_ = start()
+	}
+	// Mid-stream failures cannot change the status any more; the client sees
+	// a truncated body.
+	_ = rw.flush()
+}
+
+// fieldString renders one response field of d as text, with times in
+// RFC 3339.
+func fieldString(d *device.Device, field string) string {
+	switch field {
+	case "name":
+		return d.Name()
+	case "brand":
+		return d.Brand()
+	case "serial_number":
+		return d.SerialNumber()
+	case "state":
+		return d.State()
+	case "creation_time":
+		return d.CreationTime().UTC().Format(time.RFC3339Nano)
+	case "attributes":
+		b, _ := json.Marshal(d.Attributes())
+		return string(b)
+	case "tags":
+		return strings.Join(d.Tags(), ",")
+	default:
+		return d.ID()
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+func TestNegotiateExport(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+		status int
+	}{
+		{"", "", 0},
+		{"text/csv", mediaCSV, 0},
+		{"application/json, text/csv", "", 0},
+		{"application/json;q=0.5, text/csv", mediaCSV, 0},
+		{"text/csv;q=0.2, application/x-ndjson;q=0.9", mediaNDJSON, 0},
+		{"text/html, */*;q=0.8", "", 0},
+		{"text/*", mediaCSV, 0},
+		{"text/html", "", 406},
+		{"text/csv;q=0", "", 406},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/v1/devices", nil)
+		if c.accept != "" {
+			r.Header.Set("Accept", c.accept)
+		}
+		got, err := negotiateExport(r)
+		if c.status != 0 {
+			if de, ok := err.(*device.DomainError); !ok || de.HTTP != c.status {
+				t.Errorf("Accept %q: err = %v, want status %d", c.accept, err, c.status)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("Accept %q = %q, %v; want %q", c.accept, got, err, c.want)
+		}
+	}
+}
+
+func TestCSVCellEscapesFormulas(t *testing.T) {
+	cases := map[string]string{
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-2":                "'-2",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"iPhone":            "iPhone",
+		"":                  "",
+	}
+	for in, want := range cases {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"strings"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// responseFields are the JSON keys of deviceResponse, in output order.
+var responseFields = []string{"id", "name", "brand", "serial_number", "state", "creation_time", "attributes", "tags"}
+
+// parseFields validates the sparse fieldset parameter, e.g. "id,state".
+// An empty parameter selects every field and yields nil.
+func parseFields(r *stdhttp.Request) ([]string, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get("fields"))
+	if raw == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !isResponseField(f) {
+			return nil, &device.DomainError{
+				Code:    "invalid_fields",
+				Field:   "fields",
+				Message: `unknown field "` + f + `"; must be one of: ` + strings.Join(responseFields, ", "),
+				HTTP:    stdhttp.StatusBadRequest,
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func isResponseField(f string) bool {
+	for _, rf := range responseFields {
+		if rf == f {
+			return true
+		}
+	}
+	return false
+}
+
+// shapeResp renders d with only the requested fields, or in full when fields
+// is empty.
+func shapeResp(d *device.Device, fields []string) any {
+	full := toResp(d)
+	if len(fields) == 0 {
+		return full
+	}
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		switch f {
+		case "id":
+			out[f] = full.ID
+		case "name":
+			out[f] = full.Name
+		case "brand":
+			out[f] = full.Brand
+		case "serial_number":
+			out[f] = full.SerialNumber
+		case "state":
+			out[f] = full.State
+		case "creation_time":
+			out[f] = full.CreationTime
+		case "attributes":
+			out[f] = full.Attributes
+		case "tags":
+			out[f] = full.Tags
+		}
+	}
+	return out
+}
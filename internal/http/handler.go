@@ -2,55 +2,62 @@ package http
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
-	"time"
 
 	stdhttp "net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/pubsub"
 	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/service"
 )
 
+// maxSearchLen caps the free-text q parameter of ListDevices.
+const maxSearchLen = 200
+
+// statusClientClosedRequest is the non-standard status logged when the
+// client went away before the response was written.
+const statusClientClosedRequest = 499
+
 type Handler struct {
 	repo repository.DeviceRepository
+	// devices runs the device use cases; handlers only translate HTTP.
+	devices *service.Devices
+	// bus feeds WebSocket subscribers; see DeviceSocket.
+	bus *pubsub.Bus
 }
 
 func NewHandler(repo repository.DeviceRepository) *Handler {
-	return &Handler{repo: repo}
+	return &Handler{repo: repo, devices: service.NewDevices(repo), bus: pubsub.NewBus()}
 }
 
-// Shared response struct
-type deviceResponse struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Brand        string    `json:"brand"`
-	State        string    `json:"state"`
-	CreationTime time.Time `json:"creation_time"`
-}
+// Bus is where DeviceSocket listens for changes; the outbox relay publishes
+// to it.
+func (h *Handler) Bus() *pubsub.Bus { return h.bus }
+
+// Shared response struct; device events carry the same shape.
+type deviceResponse = device.Snapshot
 
 // Helper to convert domain to response
 func toResp(d *device.Device) deviceResponse {
-	return deviceResponse{
-		ID:           d.ID(),
-		Name:         d.Name(),
-		Brand:        d.Brand(),
-		State:        d.State(),
-		CreationTime: d.CreationTime(),
-	}
+	return d.Snapshot()
 }
 
 // --- CREATE ------------------------------------------------------------------
 
 func (h *Handler) CreateDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	var req struct {
-		Name  string `json:"name"`
-		Brand string `json:"brand"`
-		State string `json:"state,omitempty"`
+		Name         string         `json:"name"`
+		Brand        string         `json:"brand"`
+		SerialNumber string         `json:"serial_number,omitempty"`
+		State        string         `json:"state,omitempty"`
+		Attributes   map[string]any `json:"attributes,omitempty"`
+		Tags         []string       `json:"tags,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, &device.DomainError{
@@ -59,36 +66,46 @@ func (h *Handler) CreateDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		return
 	}
 
-	var (
-		d   *device.Device
-		err error
-	)
-	if strings.TrimSpace(req.State) == "" {
-		d, err = device.New(req.Name, req.Brand)
-	} else {
-		d, err = device.New(req.Name, req.Brand, req.State)
+	saved, err := h.devices.CreateDevice(r.Context(), service.CreateDeviceInput{
+		Tenant:       tenantID(r),
+		Name:         req.Name,
+		Brand:        req.Brand,
+		State:        req.State,
+		SerialNumber: req.SerialNumber,
+		Attributes:   req.Attributes,
+		Tags:         req.Tags,
+	})
+	if err != nil {
+		writeJSONError(w, err)
+		return
 	}
+	writeJSON(w, stdhttp.StatusCreated, toResp(saved))
+}
+
+// --- READ (GET by ID) --------------------------------------------------------
+
+func (h *Handler) GetDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	fields, err := parseFields(r)
 	if err != nil {
 		writeJSONError(w, err)
 		return
 	}
 
-	saved, err := h.repo.Save(context.Background(), d)
+	d, err := h.devices.GetDevice(r.Context(), chi.URLParam(r, "id"), fields)
 	if err != nil {
 		writeJSONError(w, err)
 		return
 	}
-	writeJSON(w, stdhttp.StatusCreated, toResp(saved))
+	writeJSON(w, stdhttp.StatusOK, shapeResp(d, fields))
 }
 
-// --- READ (GET by ID) --------------------------------------------------------
+// --- READ (GET by serial number) ---------------------------------------------
 
-func (h *Handler) GetDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
-	id := chi.URLParam(r, "id")
-	d, err := h.repo.FindByID(context.Background(), id)
-	if errors.Is(err, sql.ErrNoRows) {
+func (h *Handler) GetDeviceBySerial(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	d, err := findBySerial(r.Context(), h.repo, chi.URLParam(r, "brand"), chi.URLParam(r, "serial"))
+	if errors.Is(err, repository.ErrNotFound) {
 		writeJSONError(w, &device.DomainError{
-			Code: "not_found", Field: "id", Message: "device not found", HTTP: stdhttp.StatusNotFound,
+			Code: "not_found", Field: "serial_number", Message: "device not found", HTTP: stdhttp.StatusNotFound,
 		})
 		return
 	}
@@ -99,106 +116,173 @@ func (h *Handler) GetDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	writeJSON(w, stdhttp.StatusOK, toResp(d))
 }
 
+// findBySerial looks a device up by brand and serial number, both compared
+// case-insensitively. It returns a repository.NotFoundError when there is
+// none.
+func findBySerial(ctx context.Context, repo repository.DeviceRepository, brand, serial string) (*device.Device, error) {
+	serial, err := device.NormalizeSerialNumber(serial)
+	if err != nil {
+		return nil, err
+	}
+	brand = strings.TrimSpace(brand)
+	if brand == "" || serial == "" {
+		return nil, repository.NotFound("device", serial)
+	}
+	list, err := repo.FindAll(ctx, repository.ListOptions{
+		Filter: filter.AllOf(filter.Eq("brand", brand), filter.Eq("serial_number", serial)),
+		Limit:  1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("device", serial)
+	}
+	return list[0], nil
+}
+
 // --- LIST (GET all or filtered) ---------------------------------------------
 
 func (h *Handler) ListDevices(w stdhttp.ResponseWriter, r *stdhttp.Request) {
-	brand := strings.TrimSpace(r.URL.Query().Get("brand"))
-	state := strings.TrimSpace(r.URL.Query().Get("state"))
 	pageStr := r.URL.Query().Get("page")
 	limitStr := r.URL.Query().Get("limit")
+	cursorStr := r.URL.Query().Get("cursor")
 
-	var bPtr, sPtr *string
-	if brand != "" {
-		bPtr = &brand
+	opts, err := listOptions(r)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	mt, err := negotiateExport(r)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if mt != "" {
+		exportDevices(w, mt, opts.Fields, func(fn func(d *device.Device) error) error {
+			return h.repo.ForEach(r.Context(), opts, fn)
+		})
+		return
+	}
+
+	in := service.ListDevicesInput{
+		Options: opts,
+		Unpaged: pageStr == "" && limitStr == "" && cursorStr == "",
+		Cursor:  cursorStr,
 	}
-	if state != "" {
-		sPtr = &state
+	// Malformed page and limit values fall back to the defaults.
+	if p, err := strconv.ParseInt(pageStr, 10, 64); err == nil && p > 0 {
+		in.Page = int(p)
+	}
+	if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l > 0 {
+		in.Limit = int(min(l, service.MaxPageSize))
 	}
 
-	list, err := h.repo.FindAll(context.Background(), bPtr, sPtr)
+	out, err := h.devices.ListDevices(r.Context(), in)
 	if err != nil {
 		writeJSONError(w, err)
 		return
 	}
 
-	resp := []deviceResponse{}
-	for _, d := range list {
-		resp = append(resp, toResp(d))
+	items := []any{}
+	for _, d := range out.Devices {
+		items = append(items, shapeResp(d, opts.Fields))
+	}
+	if in.Unpaged {
+		writeJSON(w, stdhttp.StatusOK, items)
+		return
+	}
+
+	envelope := map[string]any{
+		"items":         items,
+		"next_page":     pageNumber(out.NextPage),
+		"previous_page": pageNumber(out.PreviousPage),
+		"next_cursor":   out.NextCursor,
 	}
+	writeJSON(w, stdhttp.StatusOK, envelope)
+}
 
-	if pageStr != "" || limitStr != "" {
-		page := 1
-		if pageStr != "" {
-			if p, err := strconv.ParseInt(pageStr, 10, 64); err == nil && p > 0 {
-				page = int(p)
-			}
-		}
-		
-		limit := 10
-		if limitStr != "" {
-			if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l > 0 {
-				limit = int(l)
-				if limit > 100 {
-					limit = 100
-				}
-			}
-		}
+// pageNumber renders a page link for the list envelope; 0 means none.
+func pageNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
 
-		total := len(resp)
-		start := (page - 1) * limit
-		end := start + limit
+// listOptions collects the list filters from the query string: the plain
+// brand/state/overdue/attr.*/tag params, the free-text q and the filter expression, all ANDed,
+// plus the sort order and sparse fieldset.
+func listOptions(r *stdhttp.Request) (repository.ListOptions, error) {
+	query := r.URL.Query()
 
-		if start >= total {
-			start = total
-		}
-		if end > total {
-			end = total
-		}
+	q := strings.TrimSpace(query.Get("q"))
+	if len(q) > maxSearchLen {
+		return repository.ListOptions{}, device.ErrInvalid("q", "q must be at most 200 characters", stdhttp.StatusBadRequest)
+	}
 
-		paged := resp[start:end]
-		
-		nextPage := ""
-		if end < total {
-			nextPage = strconv.FormatInt(int64(page+1), 10)
-		}
-		
-		prevPage := ""
-		if page > 1 {
-			prevPage = strconv.FormatInt(int64(page-1), 10)
-		}
+	expr, err := filter.Parse(query.Get("filter"))
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
 
-		envelope := map[string]any{
-			"items":         paged,
-			"next_page":     nextPage,
-			"previous_page": prevPage,
+	var plain []filter.Expr
+	if brand := strings.TrimSpace(query.Get("brand")); brand != "" {
+		plain = append(plain, filter.Eq("brand", brand))
+	}
+	if state := strings.TrimSpace(query.Get("state")); state != "" {
+		plain = append(plain, filter.Eq("state", state))
+	}
+	if raw := strings.TrimSpace(query.Get("overdue")); raw != "" {
+		overdue, err := strconv.ParseBool(raw)
+		if err != nil {
+			return repository.ListOptions{}, device.ErrInvalid("overdue", "overdue must be true or false", stdhttp.StatusBadRequest)
 		}
-		writeJSON(w, stdhttp.StatusOK, envelope)
-		return
+		plain = append(plain, filter.Comparison{Field: "overdue", Op: filter.OpEq, Values: []any{overdue}})
+	}
+	attrs, err := attributeFilters(r)
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+	plain = append(plain, attrs...)
+	tags, err := tagFilters(r)
+	if err != nil {
+		return repository.ListOptions{}, err
 	}
+	plain = append(plain, tags...)
 
-	writeJSON(w, stdhttp.StatusOK, resp)
+	keys, err := filter.ParseSort(query.Get("sort"))
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+
+	fields, err := parseFields(r)
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+
+	return repository.ListOptions{
+		Filter: filter.AllOf(append(plain, expr)...),
+		Search: q,
+		Sort:   keys,
+		Fields: fields,
+	}, nil
 }
 
 // --- UPDATE (PATCH minimal example) -----------------------------------------
 
 func (h *Handler) UpdateDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
-	id := chi.URLParam(r, "id")
-	d, err := h.repo.FindByID(context.Background(), id)
-	if errors.Is(err, sql.ErrNoRows) {
-		writeJSONError(w, &device.DomainError{
-			Code: "not_found", Field: "id", Message: "device not found", HTTP: stdhttp.StatusNotFound,
-		})
-		return
-	}
-	if err != nil {
-		writeJSONError(w, err)
-		return
-	}
-
 	var req struct {
 		Name  *string `json:"name,omitempty"`
 		Brand *string `json:"brand,omitempty"`
 		State *string `json:"state,omitempty"`
+		// An empty string clears the serial number.
+		SerialNumber *string `json:"serial_number,omitempty"`
+		// Merged into the current attributes; a null value removes the key.
+		Attributes map[string]any `json:"attributes,omitempty"`
+		// Replaces every tag when present; [] clears them.
+		Tags *[]string `json:"tags,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, &device.DomainError{
@@ -207,33 +291,16 @@ func (h *Handler) UpdateDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		return
 	}
 
-	// Business rule: cannot change name/brand if in-use
-	if d.State() == device.StateInUse && (req.Name != nil || req.Brand != nil) {
-		writeJSONError(w, device.ErrForbiddenChange("name/brand", "device is in use", stdhttp.StatusBadRequest))
-		return
-	}
-
-	// Apply updates if provided
-	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
-		if err := d.SetName(*req.Name); err != nil {
-			writeJSONError(w, err)
-			return
-		}
-	}
-	if req.Brand != nil && strings.TrimSpace(*req.Brand) != "" {
-		if err := d.SetBrand(*req.Brand); err != nil {
-			writeJSONError(w, err)
-			return
-		}
-	}
-	if req.State != nil && strings.TrimSpace(*req.State) != "" {
-		if err := d.SetState(*req.State); err != nil {
-			writeJSONError(w, err)
-			return
-		}
-	}
-
-	updated, err := h.repo.Update(context.Background(), d)
+	updated, err := h.devices.UpdateDevice(r.Context(), service.UpdateDeviceInput{
+		Tenant:       tenantID(r),
+		ID:           chi.URLParam(r, "id"),
+		Name:         req.Name,
+		Brand:        req.Brand,
+		State:        req.State,
+		SerialNumber: req.SerialNumber,
+		Attributes:   req.Attributes,
+		Tags:         req.Tags,
+	})
 	if err != nil {
 		writeJSONError(w, err)
 		return
@@ -244,25 +311,7 @@ func (h *Handler) UpdateDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 // --- DELETE ------------------------------------------------------------------
 
 func (h *Handler) DeleteDevice(w stdhttp.ResponseWriter, r *stdhttp.Request) {
-	id := chi.URLParam(r, "id")
-	d, err := h.repo.FindByID(context.Background(), id)
-	if errors.Is(err, sql.ErrNoRows) {
-		writeJSONError(w, &device.DomainError{
-			Code: "not_found", Field: "id", Message: "device not found", HTTP: stdhttp.StatusNotFound,
-		})
-		return
-	}
-	if err != nil {
-		writeJSONError(w, err)
-		return
-	}
-
-	if d.State() == device.StateInUse {
-		writeJSONError(w, device.ErrConflict("device", "cannot delete device in use"))
-		return
-	}
-
-	if err := h.repo.Delete(context.Background(), id); err != nil {
+	if err := h.devices.DeleteDevice(r.Context(), chi.URLParam(r, "id")); err != nil {
 		writeJSONError(w, err)
 		return
 	}
@@ -277,6 +326,15 @@ func writeJSON(w stdhttp.ResponseWriter, code int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// writeDeviceError is writeJSONError for handlers addressing a device by
+// id: repository.ErrNotFound becomes a device-not-found 404.
+func writeDeviceError(w stdhttp.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		err = service.ErrDeviceNotFound()
+	}
+	writeJSONError(w, err)
+}
+
 func writeJSONError(w stdhttp.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -291,9 +349,34 @@ func writeJSONError(w stdhttp.ResponseWriter, err error) {
 		return
 	}
 
-	w.WriteHeader(stdhttp.StatusInternalServerError)
-	_ = json.NewEncoder(w).Encode(map[string]any{
+	// Storage errors no handler translated itself.
+	var nf *repository.NotFoundError
+	status, body := stdhttp.StatusInternalServerError, map[string]any{
 		"code":    "internal_error",
 		"message": "unexpected error",
-	})
+	}
+	switch {
+	case errors.As(err, &nf):
+		status, body = stdhttp.StatusNotFound, map[string]any{
+			"code": "not_found", "field": "id", "message": nf.Resource + " not found",
+		}
+	case errors.Is(err, repository.ErrNotFound):
+		status, body = stdhttp.StatusNotFound, map[string]any{
+			"code": "not_found", "message": "resource not found",
+		}
+	case errors.Is(err, repository.ErrConflict):
+		status, body = stdhttp.StatusConflict, map[string]any{
+			"code": "conflict", "message": "the request conflicts with existing data",
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		status, body = stdhttp.StatusGatewayTimeout, map[string]any{
+			"code": "timeout", "message": "the request took too long",
+		}
+	case errors.Is(err, context.Canceled):
+		status, body = statusClientClosedRequest, map[string]any{
+			"code": "canceled", "message": "the request was canceled",
+		}
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
 }
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/marcboeker/go-duckdb"
+
+	duckdbrepo "github.com/leandronowras/device-api/internal/repository/duckdb"
+)
+
+func TestRequestsEndWithTheClientsContext(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	h := NewHandler(duckdbrepo.NewDeviceRepository(db))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // the client went away
+	for _, target := range []string{"/v1/devices", "/v1/devices?limit=10"} {
+		w := httptest.NewRecorder()
+		h.ListDevices(w, httptest.NewRequest("GET", target, nil).WithContext(ctx))
+		if w.Code != statusClientClosedRequest {
+			t.Errorf("GET %s: status %d, want %d", target, w.Code, statusClientClosedRequest)
+		}
+	}
+}
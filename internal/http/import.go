@@ -0,0 +1,348 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+const (
+	importModeCreate = "create"
+	importModeUpsert = "upsert"
+
+	maxImportBytes = 10 << 20 // 10 MiB
+)
+
+// errDryRun rolls back the import transaction once every row was checked.
+var errDryRun = errors.New("dry run")
+
+// importRow is one device record of an upload, in either format.
+type importRow struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Brand        string `json:"brand"`
+	State        string `json:"state"`
+	CreationTime string `json:"creation_time"`
+	// SerialNumber identifies the device when id is empty: upserts match an
+	// existing device of the same brand by it.
+	SerialNumber string `json:"serial_number"`
+	// Attributes replaces the device's attributes when present. CSV uploads
+	// carry it as a JSON object in an "attributes" column.
+	Attributes map[string]any `json:"attributes"`
+	// Tags replaces the device's tags when present. CSV uploads carry them
+	// comma-separated in a "tags" column.
+	Tags []string `json:"tags"`
+}
+
+type importRowError struct {
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type importReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Mode    string           `json:"mode"`
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []importRowError `json:"errors"`
+}
+
+// --- IMPORT (POST /v1/devices:import) ----------------------------------------
+
+func (h *Handler) ImportDevices(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	mode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
+	if mode == "" {
+		mode = importModeCreate
+	}
+	if mode != importModeCreate && mode != importModeUpsert {
+		writeJSONError(w, device.ErrInvalid("mode", "mode must be one of: create, upsert", stdhttp.StatusBadRequest))
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := stdhttp.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var next func() (importRow, error)
+	switch mt {
+	case mediaCSV:
+		next = csvImportRows(body)
+	case mediaNDJSON:
+		next = ndjsonImportRows(body)
+	default:
+		writeJSONError(w, &device.DomainError{
+			Code:    "unsupported_media_type",
+			Message: "Content-Type must be text/csv or application/x-ndjson",
+			HTTP:    stdhttp.StatusUnsupportedMediaType,
+		})
+		return
+	}
+
+	// Read the whole upload before starting the transaction, which blocks
+	// every other writer until it commits.
+	var rows []importRow
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		rows = append(rows, row)
+	}
+
+	// One transaction for the whole upload. A row that fails with a domain
+	// error has written nothing: importOne makes at most one Save or Update,
+	// and those check before they write (see repository.DeviceRepository).
+	// Any other error rolls back every row.
+	report := importReport{DryRun: dryRun, Mode: mode, Errors: []importRowError{}}
+	err := h.repo.WithTx(r.Context(), func(tx repository.DeviceRepository) error {
+		for _, row := range rows {
+			report.Total++
+
+			created, err := h.importOne(r, tx, row, mode)
+			if err != nil {
+				var derr *device.DomainError
+				if !errors.As(err, &derr) {
+					return err
+				}
+				report.Failed++
+				report.Errors = append(report.Errors, importRowError{
+					Row: report.Total, Code: derr.Code, Field: derr.Field, Message: derr.Message,
+				})
+				continue
+			}
+			if created {
+				report.Created++
+			} else {
+				report.Updated++
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, report)
+}
+
+// importOne validates a row through the domain constructors and writes it.
+// It reports whether a new device was created (false means updated).
+func (h *Handler) importOne(r *stdhttp.Request, tx repository.DeviceRepository, row importRow, mode string) (bool, error) {
+	ctx := r.Context()
+	id := strings.TrimSpace(row.ID)
+
+	var attrs device.Attributes
+	if row.Attributes != nil {
+		var err error
+		if attrs, err = device.NormalizeAttributes(row.Attributes); err != nil {
+			return false, err
+		}
+		if err := h.validateAttributes(r, attrs, row.Attributes); err != nil {
+			return false, err
+		}
+	}
+
+	var (
+		existing *device.Device
+		err      error
+	)
+	switch {
+	case id != "":
+		existing, err = tx.FindByID(ctx, id)
+	case mode == importModeUpsert && strings.TrimSpace(row.SerialNumber) != "":
+		existing, err = findBySerial(ctx, tx, row.Brand, row.SerialNumber)
+	case mode == importModeUpsert:
+		return false, device.ErrRequired("id")
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+
+	if existing == nil && id == "" {
+		d, err := device.New(row.Name, row.Brand, row.State)
+		if err != nil {
+			return false, err
+		}
+		d.SetAttributes(attrs)
+		if err := d.SetTags(row.Tags); err != nil {
+			return false, err
+		}
+		if err := d.SetSerialNumber(row.SerialNumber); err != nil {
+			return false, err
+		}
+		_, err = tx.Save(ctx, d)
+		return true, err
+	}
+
+	if existing == nil {
+		ct := time.Now().UTC()
+		if s := strings.TrimSpace(row.CreationTime); s != "" {
+			if ct, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return false, device.ErrInvalid("creation_time", "creation_time must be an RFC 3339 timestamp", stdhttp.StatusBadRequest)
+			}
+		}
+		state := row.State
+		if strings.TrimSpace(state) == "" {
+			state = device.StateAvailable
+		}
+		d, err := device.NewWithID(id, row.Name, row.Brand, state, ct)
+		if err != nil {
+			return false, err
+		}
+		d.SetAttributes(attrs)
+		if err := d.SetTags(row.Tags); err != nil {
+			return false, err
+		}
+		if err := d.SetSerialNumber(row.SerialNumber); err != nil {
+			return false, err
+		}
+		_, err = tx.Save(ctx, d)
+		return true, err
+	}
+
+	if mode != importModeUpsert {
+		return false, device.ErrConflict("device", "device "+id+" already exists")
+	}
+
+	// Name and brand go before state, as in PATCH.
+	if strings.TrimSpace(row.Name) != "" {
+		if err := existing.SetName(row.Name); err != nil {
+			return false, err
+		}
+	}
+	if strings.TrimSpace(row.Brand) != "" {
+		if err := existing.SetBrand(row.Brand); err != nil {
+			return false, err
+		}
+	}
+	if strings.TrimSpace(row.State) != "" {
+		open, err := repository.OpenAssignmentOf(ctx, tx, existing.ID())
+		if err != nil {
+			return false, err
+		}
+		if err := existing.SetState(row.State, open); err != nil {
+			return false, err
+		}
+	}
+	if row.Attributes != nil {
+		existing.SetAttributes(attrs)
+	}
+	if row.Tags != nil {
+		if err := existing.SetTags(row.Tags); err != nil {
+			return false, err
+		}
+	}
+	if strings.TrimSpace(row.SerialNumber) != "" {
+		if err := existing.SetSerialNumber(row.SerialNumber); err != nil {
+			return false, err
+		}
+	}
+	_, err = tx.Update(ctx, existing)
+	return false, err
+}
+
+func csvImportRows(body io.Reader) func() (importRow, error) {
+	cr := csv.NewReader(body)
+	cr.TrimLeadingSpace = true
+
+	var cols map[string]int
+	line := 0
+	return func() (importRow, error) {
+		if cols == nil {
+			header, err := cr.Read()
+			if err != nil {
+				return importRow{}, badImport(0, "missing CSV header row")
+			}
+			cols = map[string]int{}
+			for i, h := range header {
+				cols[strings.ToLower(strings.TrimSpace(h))] = i
+			}
+			for _, required := range []string{"name", "brand"} {
+				if _, ok := cols[required]; !ok {
+					return importRow{}, badImport(0, "CSV header must include "+required)
+				}
+			}
+		}
+
+		rec, err := cr.Read()
+		line++
+		if errors.Is(err, io.EOF) {
+			return importRow{}, io.EOF
+		}
+		if err != nil {
+			return importRow{}, badImport(line, err.Error())
+		}
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		row := importRow{
+			ID:           get("id"),
+			Name:         get("name"),
+			Brand:        get("brand"),
+			State:        get("state"),
+			CreationTime: get("creation_time"),
+			SerialNumber: get("serial_number"),
+		}
+		if raw := strings.TrimSpace(get("attributes")); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &row.Attributes); err != nil {
+				return importRow{}, badImport(line, "attributes must be a JSON object")
+			}
+		}
+		if raw := strings.TrimSpace(get("tags")); raw != "" {
+			row.Tags = strings.Split(raw, ",")
+		}
+		return row, nil
+	}
+}
+
+func ndjsonImportRows(body io.Reader) func() (importRow, error) {
+	dec := json.NewDecoder(body)
+	line := 0
+	return func() (importRow, error) {
+		var row importRow
+		err := dec.Decode(&row)
+		line++
+		if errors.Is(err, io.EOF) {
+			return importRow{}, io.EOF
+		}
+		if err != nil {
+			return importRow{}, badImport(line, "invalid JSON: "+err.Error())
+		}
+		return row, nil
+	}
+}
+
+// badImport reports an upload that cannot be parsed any further.
+func badImport(row int, reason string) *device.DomainError {
+	msg := reason
+	if row > 0 {
+		msg = "row " + strconv.Itoa(row) + ": " + reason
+	}
+	return &device.DomainError{
+		Code: "invalid_import", Field: "body", Message: msg, HTTP: stdhttp.StatusBadRequest,
+	}
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+type ticketResponse struct {
+	ID            string     `json:"id"`
+	DeviceID      string     `json:"device_id"`
+	Description   string     `json:"description"`
+	Resolution    string     `json:"resolution,omitempty"`
+	Cost          *float64   `json:"cost"`
+	PreviousState string     `json:"previous_state"`
+	OpenedAt      time.Time  `json:"opened_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+}
+
+func toTicketResp(t *device.MaintenanceTicket) ticketResponse {
+	return ticketResponse{
+		ID:            t.ID,
+		DeviceID:      t.DeviceID,
+		Description:   t.Description,
+		Resolution:    t.Resolution,
+		Cost:          t.Cost,
+		PreviousState: t.PreviousState,
+		OpenedAt:      t.OpenedAt,
+		ClosedAt:      t.ClosedAt,
+	}
+}
+
+// --- MAINTENANCE (/v1/devices/{id}/maintenance-tickets) ---------------------
+
+func (h *Handler) OpenTicket(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Description string   `json:"description"`
+		Cost        *float64 `json:"cost,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_json", Message: "invalid JSON body", HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	var t *device.MaintenanceTicket
+	err := h.withMaintenance(r.Context(), func(tx repository.DeviceRepository, store repository.MaintenanceStore) error {
+		d, err := tx.FindByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if t, err = d.OpenMaintenance(req.Description, req.Cost, time.Now().UTC()); err != nil {
+			return err
+		}
+		if _, err := tx.Update(r.Context(), d); err != nil {
+			return err
+		}
+		return store.SaveTicket(r.Context(), t)
+	})
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusCreated, toTicketResp(t))
+}
+
+func (h *Handler) CloseTicket(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, tid := chi.URLParam(r, "id"), chi.URLParam(r, "tid")
+
+	var req struct {
+		Resolution string   `json:"resolution,omitempty"`
+		Cost       *float64 `json:"cost,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_json", Message: "invalid JSON body", HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	var t *device.MaintenanceTicket
+	err := h.withMaintenance(r.Context(), func(tx repository.DeviceRepository, store repository.MaintenanceStore) error {
+		d, err := tx.FindByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if t, err = store.Ticket(r.Context(), id, tid); err != nil {
+			return errTicketNotFound(err)
+		}
+		if err := d.CloseMaintenance(t, req.Resolution, req.Cost, time.Now().UTC()); err != nil {
+			return err
+		}
+		if _, err := tx.Update(r.Context(), d); err != nil {
+			return err
+		}
+		return store.SaveTicket(r.Context(), t)
+	})
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toTicketResp(t))
+}
+
+func (h *Handler) ListTickets(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.repo.(repository.MaintenanceStore)
+	if !ok {
+		writeJSONError(w, errMaintenanceUnsupported())
+		return
+	}
+	if _, err := h.repo.FindByID(r.Context(), id); err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	list, err := store.Tickets(r.Context(), id)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	resp := []ticketResponse{}
+	for _, t := range list {
+		resp = append(resp, toTicketResp(t))
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
+
+func (h *Handler) GetTicket(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.MaintenanceStore)
+	if !ok {
+		writeJSONError(w, errMaintenanceUnsupported())
+		return
+	}
+	t, err := store.Ticket(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "tid"))
+	if err != nil {
+		writeJSONError(w, errTicketNotFound(err))
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toTicketResp(t))
+}
+
+// withMaintenance runs fn in a transaction whose repository also keeps
+// service records, so the state change and the ticket commit together.
+func (h *Handler) withMaintenance(ctx context.Context, fn func(tx repository.DeviceRepository, store repository.MaintenanceStore) error) error {
+	if _, ok := h.repo.(repository.MaintenanceStore); !ok {
+		return errMaintenanceUnsupported()
+	}
+	return h.repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		store, ok := tx.(repository.MaintenanceStore)
+		if !ok {
+			return errMaintenanceUnsupported()
+		}
+		return fn(tx, store)
+	})
+}
+
+// errTicketNotFound turns a missing ticket into a 404 so it is not
+// mistaken for a missing device.
+func errTicketNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return &device.DomainError{
+			Code: "not_found", Field: "ticket_id", Message: "maintenance ticket not found", HTTP: stdhttp.StatusNotFound,
+		}
+	}
+	return err
+}
+
+func errMaintenanceUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "maintenance tickets are not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
@@ -0,0 +1,30 @@
+package http
+
+import (
+	_ "embed"
+
+	stdhttp "net/http"
+)
+
+// openAPISpec is the OpenAPI 3.1 description of the routes in routes.go.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// docsPage renders openAPISpec in the browser. It is self-contained: no
+// script or stylesheet is loaded from elsewhere.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// OpenAPI serves the OpenAPI document.
+func (h *Handler) OpenAPI(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// Docs serves a browsable page for /openapi.json.
+func (h *Handler) Docs(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
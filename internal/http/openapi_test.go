@@ -0,0 +1,501 @@
+package http
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/leandronowras/device-api/internal/filter"
+)
+
+// openAPIDoc is the part of openapi.json the tests read.
+type openAPIDoc struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schemaObject   `json:"schemas"`
+		Parameters map[string]parameterObject `json:"parameters"`
+	} `json:"components"`
+}
+
+type schemaObject struct {
+	Ref        string                   `json:"$ref"`
+	Type       any                      `json:"type"`
+	Properties map[string]*schemaObject `json:"properties"`
+	Items      *schemaObject            `json:"items"`
+	OneOf      []*schemaObject          `json:"oneOf"`
+}
+
+type parameterObject struct {
+	Ref  string `json:"$ref"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+type operationObject struct {
+	Parameters []parameterObject `json:"parameters"`
+	Responses  map[string]struct {
+		Content map[string]struct {
+			Schema *schemaObject `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+func loadSpec(t *testing.T) openAPIDoc {
+	t.Helper()
+	var spec openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return spec
+}
+
+// routeHandlers maps "METHOD /v1/path" to the name of the Handler method
+// serving it, e.g. "Handler.ListDevices".
+func routeHandlers(t *testing.T) map[string]string {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Route("/v1", NewHandler(nil).Routes)
+	out := map[string]string{}
+	err := chi.Walk(r, func(method, route string, h stdhttp.Handler, _ ...func(stdhttp.Handler) stdhttp.Handler) error {
+		name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+		name = strings.TrimSuffix(name[strings.LastIndex(name, ").")+2:], "-fm")
+		out[method+" "+strings.TrimSuffix(route, "/")] = "Handler." + name
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	if !strings.HasPrefix(spec.OpenAPI, "3.1") {
+		t.Fatalf("openapi = %q, want 3.1.x", spec.OpenAPI)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		if !strings.HasPrefix(path, "/v1/") {
+			continue
+		}
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	routed := map[string]bool{}
+	for route := range routeHandlers(t) {
+		routed[route] = true
+	}
+
+	if missing := difference(routed, documented); len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json:\n%s", strings.Join(missing, "\n"))
+	}
+	if stale := difference(documented, routed); len(stale) > 0 {
+		t.Errorf("openapi.json describes routes that do not exist:\n%s", strings.Join(stale, "\n"))
+	}
+}
+
+// TestOpenAPIQueryParametersMatchHandlers compares each operation's query
+// parameters with the ones its handler reads, found by walking the
+// package's source from the handler through the functions it calls.
+func TestOpenAPIQueryParametersMatchHandlers(t *testing.T) {
+	spec := loadSpec(t)
+	reads := queryReads(t)
+
+	for route, handler := range routeHandlers(t) {
+		method, path, _ := strings.Cut(route, " ")
+		item := spec.Paths[path]
+		var op operationObject
+		if err := json.Unmarshal(item[strings.ToLower(method)], &op); err != nil {
+			continue // TestOpenAPIMatchesRoutes reports it
+		}
+		params := op.Parameters
+		if raw, ok := item["parameters"]; ok {
+			var shared []parameterObject
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				t.Fatalf("%s parameters: %v", path, err)
+			}
+			params = append(params, shared...)
+		}
+
+		documented := map[string]bool{}
+		for _, p := range params {
+			if p.Ref != "" {
+				var ok bool
+				if p, ok = spec.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]; !ok {
+					t.Errorf("%s: unknown parameter %s", route, p.Ref)
+					continue
+				}
+			}
+			if p.In == "query" {
+				documented[p.Name] = true
+			}
+		}
+		read := reads(handler)
+
+		if missing := difference(read, documented); len(missing) > 0 {
+			t.Errorf("%s (%s) reads query parameters openapi.json does not list: %s", route, handler, strings.Join(missing, ", "))
+		}
+		if stale := difference(documented, read); len(stale) > 0 {
+			t.Errorf("%s (%s) documents query parameters it never reads: %s", route, handler, strings.Join(stale, ", "))
+		}
+	}
+}
+
+// responseTypes pairs component schemas with the Go types handlers encode
+// for them. Responses built from maps (DevicePage, FreeBusy) and request
+// bodies, which are anonymous structs, are not covered.
+var responseTypes = map[string]any{
+	"Device":       deviceResponse{},
+	"ImportReport": importReport{},
+	"Stats":        statsResponse{},
+	"Assignment":   assignmentResponse{},
+	"Reservation":  reservationResponse{},
+	"Ticket":       ticketResponse{},
+	"Webhook":      webhookResponse{},
+	"Delivery":     deliveryResponse{},
+}
+
+// TestOpenAPISchemasMatchResponseTypes compares the properties of each
+// schema in responseTypes, and of the objects nested in it, with the JSON
+// fields of its Go type, and checks that every schema the operations
+// reference exists.
+func TestOpenAPISchemasMatchResponseTypes(t *testing.T) {
+	spec := loadSpec(t)
+	for name, v := range responseTypes {
+		s, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("openapi.json has no %s schema", name)
+			continue
+		}
+		compareSchema(t, spec, name, s, reflect.TypeOf(v))
+	}
+
+	for path, item := range spec.Paths {
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op operationObject
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+			for status, resp := range op.Responses {
+				for media, c := range resp.Content {
+					checkRefs(t, spec, strings.ToUpper(method)+" "+path+" "+status+" "+media, c.Schema)
+				}
+			}
+		}
+	}
+}
+
+func compareSchema(t *testing.T, spec openAPIDoc, where string, s *schemaObject, typ reflect.Type) {
+	t.Helper()
+	for s != nil && s.Ref != "" {
+		s = spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if s == nil {
+		return
+	}
+	switch {
+	case typ.Kind() == reflect.Slice && s.Items != nil:
+		compareSchema(t, spec, where+"[]", s.Items, typ.Elem())
+	case typ.Kind() == reflect.Struct && typ != reflect.TypeOf(time.Time{}):
+		fields := jsonFields(typ)
+		documented, encoded := map[string]bool{}, map[string]bool{}
+		for name := range s.Properties {
+			documented[name] = true
+		}
+		for name := range fields {
+			encoded[name] = true
+		}
+		if missing := difference(encoded, documented); len(missing) > 0 {
+			t.Errorf("%s: %s encodes fields openapi.json does not list: %s", where, typ, strings.Join(missing, ", "))
+		}
+		if stale := difference(documented, encoded); len(stale) > 0 {
+			t.Errorf("%s: openapi.json lists fields %s does not encode: %s", where, typ, strings.Join(stale, ", "))
+		}
+		for name, ft := range fields {
+			if p, ok := s.Properties[name]; ok {
+				compareSchema(t, spec, where+"."+name, p, ft)
+			}
+		}
+	}
+}
+
+// jsonFields returns the JSON names of typ's encoded fields, flattening
+// embedded structs as encoding/json does.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	out := map[string]reflect.Type{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for n, ft := range jsonFields(f.Type) {
+				out[n] = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out[name] = f.Type
+	}
+	return out
+}
+
+func checkRefs(t *testing.T, spec openAPIDoc, where string, s *schemaObject) {
+	t.Helper()
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		if _, ok := spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]; !ok {
+			t.Errorf("%s: unknown schema %s", where, s.Ref)
+		}
+	}
+	checkRefs(t, spec, where, s.Items)
+	for _, o := range s.OneOf {
+		checkRefs(t, spec, where, o)
+	}
+	for _, p := range s.Properties {
+		checkRefs(t, spec, where, p)
+	}
+}
+
+// funcReads is what one function does with the query string: the
+// parameters it reads by name, the package functions it calls, and which of
+// its own arguments, if any, it uses as a parameter name.
+type funcReads struct {
+	params   map[string]bool
+	calls    []*ast.CallExpr
+	callees  []string
+	forwards int
+}
+
+// queryReads parses the package's source and returns a function reporting
+// the query parameters a function reads, directly or through the package
+// functions it calls. Methods are named "Type.Method".
+func queryReads(t *testing.T) func(name string) map[string]bool {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	funcs := map[string]*funcReads{}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				name, recv := funcName(fn)
+				funcs[name] = scanFunc(fn, recv)
+			}
+		}
+	}
+
+	var resolve func(name string, seen map[string]bool) map[string]bool
+	resolve = func(name string, seen map[string]bool) map[string]bool {
+		out := map[string]bool{}
+		fr, ok := funcs[name]
+		if !ok || seen[name] {
+			return out
+		}
+		seen[name] = true
+		for p := range fr.params {
+			out[p] = true
+		}
+		for i, callee := range fr.callees {
+			for p := range resolve(callee, seen) {
+				out[p] = true
+			}
+			if c, ok := funcs[callee]; ok && c.forwards >= 0 && c.forwards < len(fr.calls[i].Args) {
+				if p, ok := stringLit(fr.calls[i].Args[c.forwards]); ok {
+					out[p] = true
+				}
+			}
+		}
+		return out
+	}
+	return func(name string) map[string]bool { return resolve(name, map[string]bool{}) }
+}
+
+// funcName names fn as queryReads does and returns its receiver's name.
+func funcName(fn *ast.FuncDecl) (name, recv string) {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name, ""
+	}
+	field := fn.Recv.List[0]
+	typ := field.Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	ident, _ := typ.(*ast.Ident)
+	if len(field.Names) > 0 {
+		recv = field.Names[0].Name
+	}
+	if ident == nil {
+		return fn.Name.Name, recv
+	}
+	return ident.Name + "." + fn.Name.Name, recv
+}
+
+func scanFunc(fn *ast.FuncDecl, recv string) *funcReads {
+	fr := &funcReads{params: map[string]bool{}, forwards: -1}
+	argIndex := map[string]int{}
+	i := 0
+	for _, field := range fn.Type.Params.List {
+		for _, n := range field.Names {
+			argIndex[n.Name] = i
+			i++
+		}
+		if len(field.Names) == 0 {
+			i++
+		}
+	}
+	recvType, _ := funcName(fn)
+	recvType, _, _ = strings.Cut(recvType, ".")
+
+	// Variables holding r.URL.Query().
+	values := map[string]bool{}
+	isValues := func(e ast.Expr) bool {
+		if id, ok := e.(*ast.Ident); ok {
+			return values[id.Name]
+		}
+		return isQueryCall(e)
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 && isQueryCall(n.Rhs[0]) {
+				if id, ok := n.Lhs[0].(*ast.Ident); ok {
+					values[id.Name] = true
+				}
+			}
+		case *ast.IndexExpr:
+			if p, ok := stringLit(n.Index); ok && isValues(n.X) {
+				fr.params[p] = true
+			}
+		case *ast.RangeStmt:
+			if isValues(n.X) {
+				// Prefixed families such as attr.<name> are documented as
+				// one deepObject parameter named after the prefix.
+				ast.Inspect(n.Body, func(m ast.Node) bool {
+					if c, ok := m.(*ast.CallExpr); ok && isPrefixCall(c) && len(c.Args) == 2 {
+						if prefix, ok := prefixValue(c.Args[1]); ok {
+							fr.params[strings.TrimSuffix(prefix, ".")] = true
+						}
+					}
+					return true
+				})
+			}
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if ok && sel.Sel.Name == "Get" && len(n.Args) == 1 && isValues(sel.X) {
+				if p, ok := stringLit(n.Args[0]); ok {
+					fr.params[p] = true
+				} else if id, ok := n.Args[0].(*ast.Ident); ok {
+					if idx, ok := argIndex[id.Name]; ok {
+						fr.forwards = idx
+					}
+				}
+			}
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				fr.calls = append(fr.calls, n)
+				fr.callees = append(fr.callees, fun.Name)
+			case *ast.SelectorExpr:
+				if id, ok := fun.X.(*ast.Ident); ok && recv != "" && id.Name == recv {
+					fr.calls = append(fr.calls, n)
+					fr.callees = append(fr.callees, recvType+"."+fun.Sel.Name)
+				}
+			}
+		}
+		return true
+	})
+	return fr
+}
+
+// isQueryCall reports whether e is <request>.URL.Query().
+func isQueryCall(e ast.Expr) bool {
+	c, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Query" {
+		return false
+	}
+	url, ok := sel.X.(*ast.SelectorExpr)
+	return ok && url.Sel.Name == "URL"
+}
+
+func isPrefixCall(c *ast.CallExpr) bool {
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	return ok && (sel.Sel.Name == "HasPrefix" || sel.Sel.Name == "CutPrefix")
+}
+
+// prefixValue resolves a prefix given as a literal or as filter.AttrPrefix.
+func prefixValue(e ast.Expr) (string, bool) {
+	if s, ok := stringLit(e); ok {
+		return s, true
+	}
+	if sel, ok := e.(*ast.SelectorExpr); ok && sel.Sel.Name == "AttrPrefix" {
+		return filter.AttrPrefix, true
+	}
+	return "", false
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// difference returns the keys of a not in b, sorted.
+func difference(a, b map[string]bool) []string {
+	var out []string
+	for k := range a {
+		if !b[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
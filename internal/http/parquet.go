@@ -0,0 +1,92 @@
+package http
+
+import (
+	"io"
+	"os"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+const (
+	mediaParquet = "application/vnd.apache.parquet"
+
+	maxParquetBytes = 100 << 20 // 100 MiB
+)
+
+// --- PARQUET (GET /v1/devices/export.parquet, POST /v1/devices/import.parquet)
+
+func (h *Handler) ExportParquet(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	pt, ok := h.repo.(repository.ParquetTransfer)
+	if !ok {
+		writeJSONError(w, errParquetUnsupported())
+		return
+	}
+
+	f, err := os.CreateTemp("", "devices-*.parquet")
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	path := f.Name()
+	_ = f.Close()
+	defer os.Remove(path)
+
+	if err := pt.ExportParquet(r.Context(), path); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", mediaParquet)
+	w.Header().Set("Content-Disposition", `attachment; filename="devices.parquet"`)
+	w.WriteHeader(stdhttp.StatusOK)
+	_, _ = io.Copy(w, f)
+}
+
+func (h *Handler) ImportParquet(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	pt, ok := h.repo.(repository.ParquetTransfer)
+	if !ok {
+		writeJSONError(w, errParquetUnsupported())
+		return
+	}
+
+	f, err := os.CreateTemp("", "devices-import-*.parquet")
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, stdhttp.MaxBytesReader(w, r.Body, maxParquetBytes))
+	_ = f.Close()
+	if err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_parquet", Field: "body", Message: "could not read upload: " + err.Error(), HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	n, err := pt.ImportParquet(r.Context(), f.Name())
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, map[string]any{"imported": n})
+}
+
+func errParquetUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "parquet transfer is not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
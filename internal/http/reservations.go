@@ -0,0 +1,319 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+const (
+	// defaultFreeBusyWindow is the span of a free/busy query without "to".
+	defaultFreeBusyWindow = 7 * 24 * time.Hour
+	maxFreeBusyWindow     = 90 * 24 * time.Hour
+)
+
+type reservationResponse struct {
+	ID          string     `json:"id"`
+	DeviceID    string     `json:"device_id"`
+	Reserver    string     `json:"reserver"`
+	Note        string     `json:"note,omitempty"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+}
+
+func toReservationResp(r *device.Reservation) reservationResponse {
+	return reservationResponse{
+		ID:          r.ID,
+		DeviceID:    r.DeviceID,
+		Reserver:    r.Reserver,
+		Note:        r.Note,
+		StartsAt:    r.StartsAt,
+		EndsAt:      r.EndsAt,
+		Status:      r.Status,
+		CreatedAt:   r.CreatedAt,
+		CancelledAt: r.CancelledAt,
+	}
+}
+
+// --- RESERVATIONS (/v1/devices/{id}/reservations, /v1/reservations) ---------
+
+func (h *Handler) CreateReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.repo.(repository.ReservationStore)
+	if !ok {
+		writeJSONError(w, errReservationsUnsupported())
+		return
+	}
+
+	var req struct {
+		Reserver string    `json:"reserver"`
+		Note     string    `json:"note,omitempty"`
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_json", Message: "invalid JSON body (times must be RFC 3339)", HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	res, err := device.NewReservation(id, req.Reserver, req.Note, req.StartsAt, req.EndsAt, time.Now().UTC())
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if err := store.CreateReservation(r.Context(), res); err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusCreated, toReservationResp(res))
+}
+
+// ListDeviceReservations lists one device's reservations; ListReservations
+// lists them across devices, optionally for one reserver. Both accept
+// from/to (RFC 3339) and a comma-separated status filter.
+func (h *Handler) ListDeviceReservations(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.repo.FindByID(r.Context(), id); err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	h.listReservations(w, r, repository.ReservationQuery{DeviceID: id})
+}
+
+func (h *Handler) ListReservations(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.listReservations(w, r, repository.ReservationQuery{
+		Reserver: strings.TrimSpace(r.URL.Query().Get("reserver")),
+	})
+}
+
+func (h *Handler) listReservations(w stdhttp.ResponseWriter, r *stdhttp.Request, rq repository.ReservationQuery) {
+	store, ok := h.repo.(repository.ReservationStore)
+	if !ok {
+		writeJSONError(w, errReservationsUnsupported())
+		return
+	}
+	query := r.URL.Query()
+
+	var err error
+	if rq.From, err = queryTime(r, "from"); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if rq.To, err = queryTime(r, "to"); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if raw := strings.TrimSpace(query.Get("status")); raw != "" {
+		for _, s := range strings.Split(raw, ",") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if !isReservationStatus(s) {
+				writeJSONError(w, device.ErrInvalid("status",
+					"status must be one of: scheduled, active, completed, cancelled, missed", stdhttp.StatusBadRequest))
+				return
+			}
+			rq.Statuses = append(rq.Statuses, s)
+		}
+	}
+
+	list, err := store.Reservations(r.Context(), rq)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	resp := []reservationResponse{}
+	for _, res := range list {
+		resp = append(resp, toReservationResp(res))
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
+
+// CancelReservation withdraws a reservation; cancelling an active one
+// releases the device at once.
+func (h *Handler) CancelReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	rid := chi.URLParam(r, "rid")
+	if _, ok := h.repo.(repository.ReservationStore); !ok {
+		writeJSONError(w, errReservationsUnsupported())
+		return
+	}
+
+	var res *device.Reservation
+	err := h.repo.WithTx(r.Context(), func(tx repository.DeviceRepository) error {
+		store := tx.(repository.ReservationStore)
+		var err error
+		if res, err = store.Reservation(r.Context(), rid); err != nil {
+			return err
+		}
+		d, err := tx.FindByID(r.Context(), res.DeviceID)
+		if err != nil {
+			return err
+		}
+		open, err := repository.OpenAssignmentOf(r.Context(), tx, d.ID())
+		if err != nil {
+			return err
+		}
+		wasInUse := d.State() == device.StateInUse
+		if err := res.Cancel(d, open, time.Now().UTC()); err != nil {
+			return err
+		}
+		if wasInUse && d.State() != device.StateInUse {
+			if _, err := tx.Update(r.Context(), d); err != nil {
+				return err
+			}
+		}
+		return store.UpdateReservation(r.Context(), res)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_found", Field: "id", Message: "reservation not found", HTTP: stdhttp.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toReservationResp(res))
+}
+
+// --- FREE/BUSY (/v1/devices/{id}/free-busy) ----------------------------------
+
+type busyInterval struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Kind          string    `json:"kind"` // "reservation" or "checkout"
+	ReservationID string    `json:"reservation_id,omitempty"`
+}
+
+type freeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusy reports when a device is booked or checked out within
+// [from, to), defaulting to the next seven days, and the gaps in between.
+// An overdue checkout counts as busy until the end of the window.
+func (h *Handler) FreeBusy(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.repo.(repository.ReservationStore)
+	if !ok {
+		writeJSONError(w, errReservationsUnsupported())
+		return
+	}
+
+	now := time.Now().UTC()
+	from, err := queryTime(r, "from")
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if from.IsZero() {
+		from = now
+	}
+	to, err := queryTime(r, "to")
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if to.IsZero() {
+		to = from.Add(defaultFreeBusyWindow)
+	}
+	if !to.After(from) || to.Sub(from) > maxFreeBusyWindow {
+		writeJSONError(w, device.ErrInvalid("to", "to must be after from and at most 90 days later", stdhttp.StatusBadRequest))
+		return
+	}
+
+	if _, err := h.repo.FindByID(r.Context(), id); err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	list, err := store.Reservations(r.Context(), repository.ReservationQuery{
+		DeviceID: id,
+		Statuses: []string{device.ReservationScheduled, device.ReservationActive},
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	busy := []busyInterval{}
+	var spans []device.Interval
+	for _, res := range list {
+		busy = append(busy, busyInterval{Start: res.StartsAt, End: res.EndsAt, Kind: "reservation", ReservationID: res.ID})
+		spans = append(spans, device.Interval{Start: res.StartsAt, End: res.EndsAt})
+	}
+	if as, ok := h.repo.(repository.AssignmentStore); ok {
+		a, err := as.OpenAssignment(r.Context(), id)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			writeJSONError(w, err)
+			return
+		}
+		if a != nil {
+			end := a.DueAt
+			if a.Overdue(now) {
+				end = to
+			}
+			if end.After(from) && a.CheckedOutAt.Before(to) {
+				busy = append(busy, busyInterval{Start: a.CheckedOutAt, End: end, Kind: "checkout"})
+				spans = append(spans, device.Interval{Start: a.CheckedOutAt, End: end})
+			}
+		}
+	}
+
+	free := []freeInterval{}
+	for _, f := range device.FreeIntervals(from, to, spans) {
+		free = append(free, freeInterval{Start: f.Start, End: f.End})
+	}
+	writeJSON(w, stdhttp.StatusOK, map[string]any{
+		"device_id": id,
+		"from":      from,
+		"to":        to,
+		"busy":      busy,
+		"free":      free,
+	})
+}
+
+// queryTime parses an optional RFC 3339 query parameter; absent yields the
+// zero time.
+func queryTime(r *stdhttp.Request, name string) (time.Time, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get(name))
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, device.ErrInvalid(name, name+" must be an RFC 3339 timestamp", stdhttp.StatusBadRequest)
+	}
+	return t.UTC(), nil
+}
+
+func isReservationStatus(s string) bool {
+	switch s {
+	case device.ReservationScheduled, device.ReservationActive, device.ReservationCompleted,
+		device.ReservationCancelled, device.ReservationMissed:
+		return true
+	default:
+		return false
+	}
+}
+
+func errReservationsUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "reservations are not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
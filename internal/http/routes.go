@@ -0,0 +1,45 @@
+package http
+
+import "github.com/go-chi/chi/v5"
+
+// Routes registers the API on r; mount it under /v1. openapi.json must
+// describe exactly these routes and the query parameters their handlers
+// read, which the tests in openapi_test.go check.
+func (h *Handler) Routes(r chi.Router) {
+	r.Post("/devices", h.CreateDevice)
+	r.Get("/devices", h.ListDevices)
+	r.Post("/devices:import", h.ImportDevices)
+	r.Get("/devices/stats", h.DeviceStats)
+	r.Get("/devices/export.parquet", h.ExportParquet)
+	r.Post("/devices/import.parquet", h.ImportParquet)
+	r.Get("/devices/by-serial/{brand}/{serial}", h.GetDeviceBySerial)
+	r.Get("/devices/events", h.DeviceEvents)
+	r.Get("/devices/socket", h.DeviceSocket)
+	r.Get("/devices/{id}", h.GetDevice)
+	r.Patch("/devices/{id}", h.UpdateDevice)
+	r.Delete("/devices/{id}", h.DeleteDevice)
+	r.Post("/devices/{id}/tags/{tag}", h.AddTag)
+	r.Delete("/devices/{id}/tags/{tag}", h.RemoveTag)
+	r.Post("/devices/{id}/checkout", h.CheckoutDevice)
+	r.Post("/devices/{id}/checkin", h.CheckinDevice)
+	r.Get("/devices/{id}/assignments", h.ListAssignments)
+	r.Post("/devices/{id}/reservations", h.CreateReservation)
+	r.Get("/devices/{id}/reservations", h.ListDeviceReservations)
+	r.Get("/devices/{id}/free-busy", h.FreeBusy)
+	r.Post("/devices/{id}/maintenance-tickets", h.OpenTicket)
+	r.Get("/devices/{id}/maintenance-tickets", h.ListTickets)
+	r.Get("/devices/{id}/maintenance-tickets/{tid}", h.GetTicket)
+	r.Post("/devices/{id}/maintenance-tickets/{tid}/close", h.CloseTicket)
+	r.Get("/reservations", h.ListReservations)
+	r.Delete("/reservations/{rid}", h.CancelReservation)
+	r.Post("/webhooks", h.CreateWebhook)
+	r.Get("/webhooks", h.ListWebhooks)
+	r.Get("/webhooks/{wid}", h.GetWebhook)
+	r.Delete("/webhooks/{wid}", h.DeleteWebhook)
+	r.Get("/webhooks/{wid}/deliveries", h.ListDeliveries)
+	r.Post("/webhooks/{wid}/deliveries/{did}/redeliver", h.Redeliver)
+
+	r.Get("/tenants/{tenant}/attribute-schema", h.GetAttributeSchema)
+	r.Put("/tenants/{tenant}/attribute-schema", h.PutAttributeSchema)
+	r.Delete("/tenants/{tenant}/attribute-schema", h.DeleteAttributeSchema)
+}
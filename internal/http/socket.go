@@ -0,0 +1,244 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// WebSocket tuning. The server pings every socketPing and gives up on a peer
+// that has not answered or sent anything for twice that.
+var (
+	socketPing      = 30 * time.Second
+	socketHeartbeat = 30 * time.Second
+	socketWriteWait = 10 * time.Second
+)
+
+const (
+	// maxSocketDevices caps how many devices one connection may watch.
+	maxSocketDevices = 1000
+	maxSocketMessage = 64 << 10
+)
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096}
+
+// socketRequest is a client message:
+//
+//	{"type": "subscribe",   "device_ids": ["..."]}  start watching devices
+//	{"type": "unsubscribe", "device_ids": ["..."]}  stop watching them
+//	{"type": "snapshot",    "device_ids": ["..."]}  current state; ids default to the subscription
+//	{"type": "heartbeat"}                           answered with a heartbeat
+type socketRequest struct {
+	Type      string   `json:"type"`
+	DeviceIDs []string `json:"device_ids"`
+
+	malformed bool
+}
+
+// socketMessage is a server message. Besides replies to requests the server
+// sends "event" messages for changes to watched devices, a "heartbeat" every
+// socketHeartbeat, and "error" messages.
+type socketMessage struct {
+	Type      string           `json:"type"`
+	ID        string           `json:"id,omitempty"`
+	DeviceIDs []string         `json:"device_ids,omitempty"`
+	Event     string           `json:"event,omitempty"`
+	DeviceID  string           `json:"device_id,omitempty"`
+	Device    *deviceResponse  `json:"device,omitempty"`
+	Devices   []deviceResponse `json:"devices,omitempty"`
+	Missing   []string         `json:"missing,omitempty"`
+	Time      *time.Time       `json:"time,omitempty"`
+	Code      string           `json:"code,omitempty"`
+	Message   string           `json:"message,omitempty"`
+}
+
+// --- WEBSOCKET (/v1/devices/socket) -------------------------------------------
+
+// DeviceSocket upgrades to a WebSocket over which a client manages a set of
+// watched devices and receives their created/updated/deleted events. A client
+// that falls more than pubsub.DefaultBuffer events behind is sent a
+// slow_consumer error and disconnected; it should reconnect and ask for a
+// snapshot.
+func (h *Handler) DeviceSocket(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response.
+		return
+	}
+	defer conn.Close()
+
+	sub := h.bus.Subscribe(pubsub.DefaultBuffer)
+	defer sub.Close()
+
+	conn.SetReadLimit(maxSocketMessage)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * socketPing))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * socketPing))
+	})
+
+	// gorilla allows one reader and one writer at a time: this goroutine
+	// reads, the loop below does all the writing.
+	requests := make(chan socketRequest)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(2 * socketPing))
+
+			var req socketRequest
+			if err := json.Unmarshal(data, &req); err != nil {
+				req = socketRequest{malformed: true}
+			}
+			select {
+			case requests <- req:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	write := func(m socketMessage) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		return conn.WriteJSON(m)
+	}
+
+	ping := time.NewTicker(socketPing)
+	defer ping.Stop()
+	heartbeat := time.NewTicker(socketHeartbeat)
+	defer heartbeat.Stop()
+
+	watched := map[string]struct{}{}
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait))
+
+		case <-heartbeat.C:
+			now := time.Now().UTC()
+			err = write(socketMessage{Type: "heartbeat", Time: &now})
+
+		case m, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					_ = write(socketMessage{
+						Type: "error", Code: "slow_consumer",
+						Message: "too many undelivered events; reconnect and request a snapshot",
+					})
+					_ = conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+						time.Now().Add(socketWriteWait))
+				}
+				return
+			}
+			if _, ok := watched[m.DeviceID]; !ok {
+				continue
+			}
+			snap := m.Device
+			err = write(socketMessage{Type: "event", ID: m.ID, Event: m.Type, DeviceID: m.DeviceID, Device: &snap})
+
+		case req := <-requests:
+			err = write(h.socketReply(r, watched, req))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// socketReply applies req to watched and returns the answer.
+func (h *Handler) socketReply(r *stdhttp.Request, watched map[string]struct{}, req socketRequest) socketMessage {
+	if req.malformed {
+		return socketError("invalid_json", "invalid JSON message")
+	}
+	ids := make([]string, 0, len(req.DeviceIDs))
+	for _, id := range req.DeviceIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	switch req.Type {
+	case "subscribe":
+		if len(ids) == 0 {
+			return socketError("invalid_device_ids", "device_ids is required")
+		}
+		added := 0
+		for _, id := range ids {
+			if _, ok := watched[id]; !ok {
+				added++
+			}
+		}
+		if len(watched)+added > maxSocketDevices {
+			return socketError("invalid_device_ids", "a connection may watch at most 1000 devices")
+		}
+		for _, id := range ids {
+			watched[id] = struct{}{}
+		}
+		return socketMessage{Type: "subscribed", DeviceIDs: sortedKeys(watched)}
+
+	case "unsubscribe":
+		if len(ids) == 0 {
+			return socketError("invalid_device_ids", "device_ids is required")
+		}
+		for _, id := range ids {
+			delete(watched, id)
+		}
+		return socketMessage{Type: "unsubscribed", DeviceIDs: sortedKeys(watched)}
+
+	case "snapshot":
+		if len(ids) == 0 {
+			ids = sortedKeys(watched)
+		}
+		if len(ids) > maxSocketDevices {
+			return socketError("invalid_device_ids", "a snapshot covers at most 1000 devices")
+		}
+		reply := socketMessage{Type: "snapshot", Devices: []deviceResponse{}}
+		for _, id := range ids {
+			d, err := h.repo.FindByID(r.Context(), id)
+			if errors.Is(err, repository.ErrNotFound) {
+				reply.Missing = append(reply.Missing, id)
+				continue
+			}
+			if err != nil {
+				return socketError("internal_error", "unexpected error")
+			}
+			reply.Devices = append(reply.Devices, toResp(d))
+		}
+		return reply
+
+	case "heartbeat":
+		now := time.Now().UTC()
+		return socketMessage{Type: "heartbeat", Time: &now}
+
+	}
+	return socketError("invalid_type", "type must be subscribe, unsubscribe, snapshot or heartbeat")
+}
+
+func socketError(code, message string) socketMessage {
+	return socketMessage{Type: "error", Code: code, Message: message}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
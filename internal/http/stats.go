@@ -0,0 +1,115 @@
+package http
+
+import (
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+type brandCountResponse struct {
+	Brand string `json:"brand"`
+	Count int    `json:"count"`
+}
+
+type stateCountResponse struct {
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+type brandStateCountResponse struct {
+	Brand string `json:"brand"`
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+type bucketResponse struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type histogramResponse struct {
+	Granularity string           `json:"granularity"`
+	Buckets     []bucketResponse `json:"buckets"`
+}
+
+// stateDurationResponse gives durations in seconds.
+type stateDurationResponse struct {
+	State   string  `json:"state"`
+	Stays   int     `json:"stays"`
+	Open    int     `json:"open"`
+	Total   float64 `json:"total_seconds"`
+	Average float64 `json:"avg_seconds"`
+	Median  float64 `json:"p50_seconds"`
+	P90     float64 `json:"p90_seconds"`
+	Max     float64 `json:"max_seconds"`
+}
+
+type statsResponse struct {
+	Total        int                       `json:"total"`
+	ByBrand      []brandCountResponse      `json:"by_brand"`
+	ByState      []stateCountResponse      `json:"by_state"`
+	ByBrandState []brandStateCountResponse `json:"by_brand_state"`
+	Created      histogramResponse         `json:"created"`
+	TimeInState  []stateDurationResponse   `json:"time_in_state"`
+}
+
+// --- STATS (GET /v1/devices/stats) -------------------------------------------
+
+func (h *Handler) DeviceStats(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	sr, ok := h.repo.(repository.StatsReader)
+	if !ok {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_implemented", Message: "stats are not supported by this storage backend", HTTP: stdhttp.StatusNotImplemented,
+		})
+		return
+	}
+
+	granularity := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("granularity")))
+	switch granularity {
+	case "":
+		granularity = repository.GranularityDay
+	case repository.GranularityDay, repository.GranularityWeek, repository.GranularityMonth:
+	default:
+		writeJSONError(w, device.ErrInvalid("granularity", "granularity must be one of: day, week, month", stdhttp.StatusBadRequest))
+		return
+	}
+
+	stats, err := sr.Stats(r.Context(), granularity)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	resp := statsResponse{
+		Total:        stats.Total,
+		ByBrand:      []brandCountResponse{},
+		ByState:      []stateCountResponse{},
+		ByBrandState: []brandStateCountResponse{},
+		Created:      histogramResponse{Granularity: granularity, Buckets: []bucketResponse{}},
+		TimeInState:  []stateDurationResponse{},
+	}
+	for _, c := range stats.ByBrand {
+		resp.ByBrand = append(resp.ByBrand, brandCountResponse{Brand: c.Brand, Count: c.Count})
+	}
+	for _, c := range stats.ByState {
+		resp.ByState = append(resp.ByState, stateCountResponse{State: c.State, Count: c.Count})
+	}
+	for _, c := range stats.ByBrandState {
+		resp.ByBrandState = append(resp.ByBrandState, brandStateCountResponse{Brand: c.Brand, State: c.State, Count: c.Count})
+	}
+	for _, b := range stats.Created {
+		resp.Created.Buckets = append(resp.Created.Buckets, bucketResponse{Start: b.Start, Count: b.Count})
+	}
+	for _, sd := range stats.TimeInState {
+		resp.TimeInState = append(resp.TimeInState, stateDurationResponse{
+			State: sd.State, Stays: sd.Stays, Open: sd.Open,
+			Total: sd.Total.Seconds(), Average: sd.Average.Seconds(), Median: sd.Median.Seconds(),
+			P90: sd.P90.Seconds(), Max: sd.Max.Seconds(),
+		})
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"errors"
+	"strings"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// tagFilters turns the tag params into filter terms: every tag=<t> must be
+// present, and with any_tag=<a>,<b> at least one of the listed tags must be.
+func tagFilters(r *stdhttp.Request) ([]filter.Expr, error) {
+	query := r.URL.Query()
+
+	var out []filter.Expr
+	for _, raw := range query["tag"] {
+		t, err := device.NormalizeTag(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, filter.Eq("tag", t))
+	}
+
+	var anyOf []string
+	for _, raw := range query["any_tag"] {
+		for _, part := range strings.Split(raw, ",") {
+			t, err := device.NormalizeTag(part)
+			if err != nil {
+				return nil, err
+			}
+			anyOf = append(anyOf, t)
+		}
+	}
+	if len(anyOf) > 0 {
+		out = append(out, filter.In("tag", anyOf...))
+	}
+	return out, nil
+}
+
+// --- TAGS (/v1/devices/{id}/tags/{tag}) --------------------------------------
+
+func (h *Handler) AddTag(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.changeTags(w, r, (*device.Device).AddTag)
+}
+
+func (h *Handler) RemoveTag(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.changeTags(w, r, (*device.Device).RemoveTag)
+}
+
+// changeTags applies change to the device's tags and responds with the
+// updated device. Both operations are idempotent.
+func (h *Handler) changeTags(w stdhttp.ResponseWriter, r *stdhttp.Request, change func(*device.Device, string) error) {
+	id := chi.URLParam(r, "id")
+	tag := chi.URLParam(r, "tag")
+
+	var updated *device.Device
+	err := h.repo.WithTx(r.Context(), func(tx repository.DeviceRepository) error {
+		d, err := tx.FindByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := change(d, tag); err != nil {
+			return err
+		}
+		updated, err = tx.Update(r.Context(), d)
+		return err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_found", Field: "id", Message: "device not found", HTTP: stdhttp.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toResp(updated))
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+type webhookResponse struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// The secret is write-only and never echoed back.
+func toWebhookResp(s *webhook.Subscription) webhookResponse {
+	return webhookResponse{ID: s.ID, URL: s.URL, EventTypes: s.EventTypes, CreatedAt: s.CreatedAt}
+}
+
+type deliveryResponse struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func toDeliveryResp(d *webhook.Delivery) deliveryResponse {
+	resp := deliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+		Payload:        d.Payload,
+	}
+	if d.Status == webhook.StatusPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+// --- WEBHOOKS (/v1/webhooks) --------------------------------------------------
+
+func (h *Handler) CreateWebhook(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+
+	var req struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, &device.DomainError{
+			Code: "invalid_json", Message: "invalid JSON body", HTTP: stdhttp.StatusBadRequest,
+		})
+		return
+	}
+
+	s, err := webhook.NewSubscription(req.URL, req.Secret, req.EventTypes, time.Now())
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if err := store.CreateWebhook(r.Context(), s); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusCreated, toWebhookResp(s))
+}
+
+func (h *Handler) ListWebhooks(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+	list, err := store.Webhooks(r.Context())
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	resp := make([]webhookResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, toWebhookResp(s))
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
+
+func (h *Handler) GetWebhook(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+	s, err := store.Webhook(r.Context(), chi.URLParam(r, "wid"))
+	if err != nil {
+		writeJSONError(w, errWebhookNotFound(err))
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toWebhookResp(s))
+}
+
+func (h *Handler) DeleteWebhook(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+	if err := store.DeleteWebhook(r.Context(), chi.URLParam(r, "wid")); err != nil {
+		writeJSONError(w, errWebhookNotFound(err))
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// ListDeliveries is the delivery log of one subscription, newest first;
+// status=dead lists its dead letters.
+func (h *Handler) ListDeliveries(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+	wid := chi.URLParam(r, "wid")
+	if _, err := store.Webhook(r.Context(), wid); err != nil {
+		writeJSONError(w, errWebhookNotFound(err))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusDead:
+	default:
+		writeJSONError(w, device.ErrInvalid("status", "status must be pending, delivered or dead", stdhttp.StatusBadRequest))
+		return
+	}
+
+	list, err := store.Deliveries(r.Context(), wid, status)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	resp := make([]deliveryResponse, 0, len(list))
+	for _, d := range list {
+		resp = append(resp, toDeliveryResp(d))
+	}
+	writeJSON(w, stdhttp.StatusOK, resp)
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts, typically
+// a dead letter once the receiver is fixed.
+func (h *Handler) Redeliver(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	store, ok := h.repo.(repository.WebhookStore)
+	if !ok {
+		writeJSONError(w, errWebhooksUnsupported())
+		return
+	}
+	d, err := store.Delivery(r.Context(), chi.URLParam(r, "wid"), chi.URLParam(r, "did"))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeJSONError(w, &device.DomainError{
+			Code: "not_found", Field: "delivery_id", Message: "delivery not found", HTTP: stdhttp.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	if d.Status == webhook.StatusPending {
+		writeJSONError(w, device.ErrConflict("delivery", "delivery is still pending"))
+		return
+	}
+	d.Redeliver(time.Now())
+	if err := store.SaveDelivery(r.Context(), d); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, toDeliveryResp(d))
+}
+
+// errWebhookNotFound turns a missing subscription into a 404.
+func errWebhookNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return &device.DomainError{
+			Code: "not_found", Field: "webhook_id", Message: "webhook not found", HTTP: stdhttp.StatusNotFound,
+		}
+	}
+	return err
+}
+
+func errWebhooksUnsupported() *device.DomainError {
+	return &device.DomainError{
+		Code:    "not_implemented",
+		Message: "webhooks are not supported by this storage backend",
+		HTTP:    stdhttp.StatusNotImplemented,
+	}
+}
@@ -0,0 +1,168 @@
+// Package outbox relays committed device changes from the repository's
+// outbox to the parts of the system that react to them.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// DefaultInterval is how often Run polls the outbox.
+const DefaultInterval = 500 * time.Millisecond
+
+const batch = 100
+
+const (
+	// MaxAttempts is how often a message is offered to the sinks before it
+	// is dead-lettered, so one message no sink can take does not hold up
+	// the rest for good. With retryDelay that is about four minutes.
+	MaxAttempts = 10
+	// OutboxRetention is how long dispatched messages are kept.
+	OutboxRetention = 24 * time.Hour
+	// EventRetention is how long the change log keeps events; see
+	// repository.EventLog.PruneEvents.
+	EventRetention = 90 * 24 * time.Hour
+
+	pruneEvery = time.Hour
+)
+
+// retryDelay is the wait after the given number of failed attempts: 1s,
+// 2s, 4s, ... capped at one minute.
+func retryDelay(failures int) time.Duration {
+	d := time.Second << min(failures-1, 6)
+	return min(d, time.Minute)
+}
+
+// Sink receives relayed changes. Delivery is at least once: a message is
+// offered to every sink again until all of them have taken it, so sinks
+// must tolerate repeats, e.g. by deduping on the message ID.
+type Sink interface {
+	Dispatch(ctx context.Context, m repository.OutboxMessage) error
+}
+
+// Relay hands outbox messages to its sinks in commit order. A message is
+// marked dispatched once every sink has taken it; when a sink fails, the
+// relay stops and retries from that message after a backoff, so later
+// changes never overtake it. After MaxAttempts the message is dead-lettered
+// and the relay moves on. Run also prunes dispatched messages and old
+// change-log events every hour.
+type Relay struct {
+	repo     repository.DeviceRepository
+	sinks    []Sink
+	interval time.Duration
+	now      func() time.Time
+
+	// mu keeps ticks from overlapping, e.g. Run and a test calling Tick.
+	mu sync.Mutex
+}
+
+// NewRelay returns a relay polling every interval; zero means
+// DefaultInterval.
+func NewRelay(repo repository.DeviceRepository, interval time.Duration, sinks ...Sink) *Relay {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Relay{repo: repo, sinks: sinks, interval: interval, now: time.Now}
+}
+
+// Run ticks until ctx is cancelled. It does nothing if the repository has
+// no outbox.
+func (r *Relay) Run(ctx context.Context) {
+	if _, ok := r.repo.(repository.Outbox); !ok {
+		return
+	}
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	var pruned time.Time
+	for {
+		if err := r.Tick(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if now := r.now(); now.Sub(pruned) >= pruneEvery {
+			pruned = now
+			if err := r.Prune(ctx, now); err != nil && ctx.Err() == nil {
+				log.Printf("outbox relay: prune: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick relays every pending message.
+func (r *Relay) Tick(ctx context.Context) error {
+	box, ok := r.repo.(repository.Outbox)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		pending, err := box.PendingOutbox(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			now := r.now()
+			if m.RetryAt.After(now) {
+				// Backing off; nothing behind it may overtake it.
+				return nil
+			}
+			if err := r.dispatch(ctx, m); err != nil {
+				failures := m.Attempts + 1
+				dead := failures >= MaxAttempts
+				if err := box.MarkFailed(ctx, m.Seq, err.Error(), now.Add(retryDelay(failures)), dead); err != nil {
+					return err
+				}
+				if !dead {
+					return err
+				}
+				log.Printf("outbox relay: dead-lettered after %d attempts: %v", failures, err)
+				continue
+			}
+			if err := box.MarkDispatched(ctx, m.Seq, now); err != nil {
+				return err
+			}
+		}
+		if len(pending) < batch {
+			return nil
+		}
+	}
+}
+
+func (r *Relay) dispatch(ctx context.Context, m repository.OutboxMessage) error {
+	for _, s := range r.sinks {
+		if err := s.Dispatch(ctx, m); err != nil {
+			return fmt.Errorf("message %s: %T: %w", m.ID, s, err)
+		}
+	}
+	return nil
+}
+
+// Prune deletes messages dispatched more than OutboxRetention before now
+// and, if the repository keeps a change log, events older than
+// EventRetention.
+func (r *Relay) Prune(ctx context.Context, now time.Time) error {
+	box, ok := r.repo.(repository.Outbox)
+	if !ok {
+		return nil
+	}
+	if _, err := box.PruneOutbox(ctx, now.Add(-OutboxRetention)); err != nil {
+		return err
+	}
+	if events, ok := r.repo.(repository.EventLog); ok {
+		if _, err := events.PruneEvents(ctx, now.Add(-EventRetention)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
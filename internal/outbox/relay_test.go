@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// memOutbox is an in-memory outbox; the embedded interface is never called.
+type memOutbox struct {
+	repository.DeviceRepository
+	msgs       []repository.OutboxMessage
+	dispatched map[int64]bool
+	dead       map[int64]bool
+}
+
+func (o *memOutbox) PendingOutbox(_ context.Context, limit int) ([]repository.OutboxMessage, error) {
+	var list []repository.OutboxMessage
+	for _, m := range o.msgs {
+		if !o.dispatched[m.Seq] && !o.dead[m.Seq] && len(list) < limit {
+			list = append(list, m)
+		}
+	}
+	return list, nil
+}
+
+func (o *memOutbox) MarkDispatched(_ context.Context, seq int64, _ time.Time) error {
+	o.dispatched[seq] = true
+	return nil
+}
+
+func (o *memOutbox) MarkFailed(_ context.Context, seq int64, _ string, retryAt time.Time, dead bool) error {
+	for i := range o.msgs {
+		if o.msgs[i].Seq == seq {
+			o.msgs[i].Attempts++
+			o.msgs[i].RetryAt = retryAt
+		}
+	}
+	if dead {
+		o.dead[seq] = true
+	}
+	return nil
+}
+
+func (o *memOutbox) PruneOutbox(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func newMemOutbox(ids ...string) *memOutbox {
+	box := &memOutbox{dispatched: map[int64]bool{}, dead: map[int64]bool{}}
+	for i, id := range ids {
+		box.msgs = append(box.msgs, repository.OutboxMessage{ID: id, Seq: int64(i + 1), Payload: []byte(`{}`)})
+	}
+	return box
+}
+
+type recordingSink struct {
+	got  []string
+	fail map[string]int // message ID -> failures left
+}
+
+func (s *recordingSink) Dispatch(_ context.Context, m repository.OutboxMessage) error {
+	if s.fail[m.ID] > 0 {
+		s.fail[m.ID]--
+		return errors.New("sink down")
+	}
+	s.got = append(s.got, m.ID)
+	return nil
+}
+
+func TestRelayRetriesInOrderUntilEverySinkTookTheMessage(t *testing.T) {
+	box := newMemOutbox("a", "b", "c")
+	healthy := &recordingSink{}
+	flaky := &recordingSink{fail: map[string]int{"b": 1}}
+	r := NewRelay(box, 0, healthy, flaky)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	if err := r.Tick(context.Background()); err == nil {
+		t.Fatal("expected the flaky sink's error")
+	}
+	if box.dispatched[2] || box.dispatched[3] {
+		t.Fatal("messages after the failure were dispatched")
+	}
+
+	// Still backing off: nothing moves.
+	if err := r.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(flaky.got) != 1 {
+		t.Fatalf("retried before the backoff: %v", flaky.got)
+	}
+
+	now = now.Add(time.Second)
+	if err := r.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(flaky.got, ","); got != "a,b,c" {
+		t.Fatalf("flaky sink got %s", got)
+	}
+	// At least once: the healthy sink sees b again.
+	if got := strings.Join(healthy.got, ","); got != "a,b,b,c" {
+		t.Fatalf("healthy sink got %s", got)
+	}
+	if len(box.dispatched) != 3 {
+		t.Fatalf("dispatched %v", box.dispatched)
+	}
+}
+
+func TestRelayDeadLettersAMessageNoSinkTakes(t *testing.T) {
+	box := newMemOutbox("a", "b")
+	sink := &recordingSink{fail: map[string]int{"a": MaxAttempts + 1}}
+	r := NewRelay(box, 0, sink)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < MaxAttempts-1; i++ {
+		if err := r.Tick(context.Background()); err == nil {
+			t.Fatalf("attempt %d: expected the sink's error", i+1)
+		}
+		now = now.Add(time.Minute)
+	}
+	if box.dead[1] || len(sink.got) != 0 {
+		t.Fatalf("gave up early: dead=%v got=%v", box.dead, sink.got)
+	}
+	if err := r.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !box.dead[1] || box.dispatched[1] {
+		t.Fatalf("a not dead-lettered: dead=%v dispatched=%v", box.dead, box.dispatched)
+	}
+	if got := strings.Join(sink.got, ","); got != "b" || !box.dispatched[2] {
+		t.Fatalf("b held up behind the dead letter: got %s", got)
+	}
+}
+
+func TestRetryDelayDoublesUpToAMinute(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 6: 32 * time.Second, 7: time.Minute, 20: time.Minute} {
+		if got := retryDelay(failures); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestWriterSinkWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewWriterSink(&buf)
+	m := repository.OutboxMessage{ID: "m1", Type: "updated", DeviceID: "d1", PreviousState: "available", Payload: []byte(`{"id":"d1"}`)}
+	if err := s.Dispatch(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	line := buf.String()
+	for _, want := range []string{`"id":"m1"`, `"type":"updated"`, `"previous_state":"available"`, `"device":{"id":"d1"}`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("missing %s in %s", want, line)
+		}
+	}
+	if !strings.HasSuffix(line, "\n") {
+		t.Error("line not terminated")
+	}
+}
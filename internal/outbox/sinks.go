@@ -0,0 +1,114 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/pubsub"
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+// BusSink publishes changes to in-process subscribers such as WebSocket
+// connections.
+type BusSink struct {
+	bus *pubsub.Bus
+}
+
+func NewBusSink(bus *pubsub.Bus) *BusSink {
+	return &BusSink{bus: bus}
+}
+
+func (s *BusSink) Dispatch(_ context.Context, m repository.OutboxMessage) error {
+	var snap device.Snapshot
+	if err := json.Unmarshal(m.Payload, &snap); err != nil {
+		return err
+	}
+	s.bus.Publish(pubsub.Message{ID: m.ID, Type: m.Type, DeviceID: m.DeviceID, Device: snap})
+	return nil
+}
+
+// WebhookSink queues a delivery of every webhook event a change produces
+// to each subscription that asked for it. Event IDs derive from the
+// message ID, so a repeated message queues nothing new. It does nothing if
+// the repository does not store webhooks.
+type WebhookSink struct {
+	repo repository.DeviceRepository
+}
+
+func NewWebhookSink(repo repository.DeviceRepository) *WebhookSink {
+	return &WebhookSink{repo: repo}
+}
+
+func (s *WebhookSink) Dispatch(ctx context.Context, m repository.OutboxMessage) error {
+	store, ok := s.repo.(repository.WebhookStore)
+	if !ok {
+		return nil
+	}
+	subs, err := store.Webhooks(ctx)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	var snap device.Snapshot
+	if err := json.Unmarshal(m.Payload, &snap); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, t := range webhook.EventTypes(m.Type, m.PreviousState, snap.State) {
+		e := webhook.Event{
+			ID:            webhook.EventID(m.ID, t),
+			Type:          t,
+			OccurredAt:    m.OccurredAt,
+			PreviousState: m.PreviousState,
+			Device:        snap,
+		}
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			if !sub.Wants(t) {
+				continue
+			}
+			if err := store.EnqueueDelivery(ctx, webhook.NewDelivery(sub, e.ID, t, body, now)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriterSink writes each change as a line of JSON, e.g. to stdout for log
+// shipping.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Dispatch(_ context.Context, m repository.OutboxMessage) error {
+	line, err := json.Marshal(struct {
+		ID            string          `json:"id"`
+		Type          string          `json:"type"`
+		DeviceID      string          `json:"device_id"`
+		PreviousState string          `json:"previous_state,omitempty"`
+		OccurredAt    time.Time       `json:"occurred_at"`
+		Device        json.RawMessage `json:"device"`
+		Events        json.RawMessage `json:"events,omitempty"`
+	}{m.ID, m.Type, m.DeviceID, m.PreviousState, m.OccurredAt, m.Payload, m.DomainEvents})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,93 @@
+// Package pubsub is an in-process fan-out of device changes to live
+// subscribers such as WebSocket connections.
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// DefaultBuffer is how many messages a subscriber may fall behind by before
+// it is dropped.
+const DefaultBuffer = 64
+
+// Message is one device change. ID is the outbox message ID, which stays
+// the same if the change is published again; Type is one of the repository
+// event types (created, updated, deleted).
+type Message struct {
+	ID       string
+	Type     string
+	DeviceID string
+	Device   device.Snapshot
+}
+
+// Bus delivers every published message to every subscriber. Publish never
+// blocks: a subscriber whose buffer is full is dropped, its channel closed
+// and Dropped set, so one slow consumer cannot stall writers or the others.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives messages on C until it is closed, either by Close
+// or by the bus when it falls behind.
+type Subscription struct {
+	C <-chan Message
+
+	c       chan Message
+	bus     *Bus
+	dropped bool
+}
+
+// Subscribe registers a subscriber with room for buffer pending messages;
+// zero or less means DefaultBuffer.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	c := make(chan Message, buffer)
+	s := &Subscription{C: c, c: c, bus: b}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *Bus) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		select {
+		case s.c <- m:
+		default:
+			s.dropped = true
+			delete(b.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// Close unsubscribes s. It is safe to call more than once, and after the
+// bus dropped s.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.c)
+	}
+}
+
+// Dropped reports whether the bus closed s because it fell behind. Check it
+// once C is closed.
+func (s *Subscription) Dropped() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,55 @@
+package pubsub
+
+import "testing"
+
+func TestBusFansOut(t *testing.T) {
+	b := NewBus()
+	s1, s2 := b.Subscribe(1), b.Subscribe(1)
+	defer s1.Close()
+	defer s2.Close()
+
+	b.Publish(Message{Type: "created", DeviceID: "d1"})
+
+	for _, s := range []*Subscription{s1, s2} {
+		if m := <-s.C; m.DeviceID != "d1" {
+			t.Fatalf("got %+v", m)
+		}
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	b := NewBus()
+	slow, fast := b.Subscribe(1), b.Subscribe(2)
+	defer fast.Close()
+
+	b.Publish(Message{DeviceID: "d1"})
+	b.Publish(Message{DeviceID: "d2"})
+
+	if m, ok := <-slow.C; !ok || m.DeviceID != "d1" {
+		t.Fatalf("buffered message lost: %+v %v", m, ok)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber not closed")
+	}
+	if !slow.Dropped() {
+		t.Fatal("Dropped() = false")
+	}
+	slow.Close() // no panic on double close
+
+	if len(fast.C) != 2 || fast.Dropped() {
+		t.Fatalf("fast subscriber affected: len=%d dropped=%v", len(fast.C), fast.Dropped())
+	}
+}
+
+func TestCloseUnsubscribes(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe(1)
+	s.Close()
+	b.Publish(Message{DeviceID: "d1"})
+	if _, ok := <-s.C; ok {
+		t.Fatal("closed subscription received a message")
+	}
+	if s.Dropped() {
+		t.Fatal("Close reported as dropped")
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// AssignmentStore keeps the checkout history of devices. Callers type-assert
+// for it; inside WithTx the transaction-bound repository implements it too.
+type AssignmentStore interface {
+	// SaveAssignment inserts a new assignment or records the check-in of an
+	// existing one.
+	SaveAssignment(ctx context.Context, a *device.Assignment) error
+	// OpenAssignment returns the device's current assignment, or
+	// ErrNotFound when it is not checked out.
+	OpenAssignment(ctx context.Context, deviceID string) (*device.Assignment, error)
+	// Assignments lists a device's assignments, newest first.
+	Assignments(ctx context.Context, deviceID string) ([]*device.Assignment, error)
+}
+
+// OpenAssignmentOf returns the device's open assignment through repo, or nil
+// when it is not checked out or repo does not keep assignments.
+func OpenAssignmentOf(ctx context.Context, repo DeviceRepository, deviceID string) (*device.Assignment, error) {
+	store, ok := repo.(AssignmentStore)
+	if !ok {
+		return nil, nil
+	}
+	a, err := store.OpenAssignment(ctx, deviceID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return a, err
+}
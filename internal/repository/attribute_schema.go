@@ -0,0 +1,12 @@
+package repository
+
+import "context"
+
+// AttributeSchemaStore keeps one attribute JSON Schema document per tenant.
+// Callers type-assert for it; a lookup for a tenant without a schema returns
+// ErrNotFound.
+type AttributeSchemaStore interface {
+	AttributeSchema(ctx context.Context, tenant string) ([]byte, error)
+	PutAttributeSchema(ctx context.Context, tenant string, schema []byte) error
+	DeleteAttributeSchema(ctx context.Context, tenant string) error
+}
@@ -4,12 +4,68 @@ import (
 	"context"
 
 	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
 )
 
+// ListOptions selects and orders the devices returned by FindAll and ForEach.
+// New criteria belong here rather than in the method signatures.
+type ListOptions struct {
+	// Filter narrows the result set; nil matches every device.
+	Filter filter.Expr
+	// Search keeps only devices whose name or brand match this free text and
+	// orders them by relevance when Sort is empty.
+	Search string
+	// Sort orders the results; empty means newest first (or by relevance
+	// when searching). Ties are always broken by id so paging is stable.
+	Sort []filter.SortKey
+	// Fields limits the columns loaded to these response fields (plus id and
+	// the sort keys). Devices come back partially populated; empty loads all.
+	Fields []string
+
+	// Limit caps the number of results (0 = no limit). Offset skips rows;
+	// After resumes right behind a previously returned device instead.
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// Cursor marks a position in a sorted listing: the sort-key values of the last
+// device seen, aligned with ListOptions.Sort (or the default sort), plus its
+// id as the tiebreak.
+type Cursor struct {
+	Values []any
+	ID     string
+}
+
+// DefaultSort is the order used when ListOptions.Sort is empty.
+var DefaultSort = []filter.SortKey{{Field: "creation_time", Desc: true}}
+
 type DeviceRepository interface {
 	Save(ctx context.Context, d *device.Device) (*device.Device, error)
 	FindByID(ctx context.Context, id string) (*device.Device, error)
-	FindAll(ctx context.Context, brand, state *string) ([]*device.Device, error)
+	FindAll(ctx context.Context, opts ListOptions) ([]*device.Device, error)
+	// ForEach streams the devices FindAll would return to fn, one at a time,
+	// without buffering the result set. Iteration stops at the first error.
+	ForEach(ctx context.Context, opts ListOptions, fn func(d *device.Device) error) error
 	Update(ctx context.Context, d *device.Device) (*device.Device, error)
-	Delete(ctx context.Context, id string) error
+	// Delete removes d and everything recorded against it. The change is
+	// logged with the events pulled from d, so callers call d.Delete first.
+	Delete(ctx context.Context, d *device.Device) error
+
+	// WithTx runs fn inside a single unit of work. The repository passed to fn
+	// is bound to that unit of work; it commits when fn returns nil and rolls
+	// back otherwise. Save, Update and Delete make every check that can fail
+	// with a *device.DomainError before they write, so fn may carry on past
+	// such an error without committing half of the failed call.
+	WithTx(ctx context.Context, fn func(tx DeviceRepository) error) error
+}
+
+// ParquetTransfer is implemented by backends that can move the device table
+// to and from Parquet files natively. Callers type-assert for it.
+type ParquetTransfer interface {
+	// ExportParquet writes every device to a Parquet file at path.
+	ExportParquet(ctx context.Context, path string) error
+	// ImportParquet validates and inserts every device found in the Parquet
+	// file at path, returning how many were imported. It is all-or-nothing.
+	ImportParquet(ctx context.Context, path string) (int, error)
 }
@@ -0,0 +1,79 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.AssignmentStore = (*deviceRepo)(nil)
+
+func (r *deviceRepo) SaveAssignment(ctx context.Context, a *device.Assignment) error {
+	var checkedIn any
+	if a.CheckedInAt != nil {
+		checkedIn = *a.CheckedInAt
+	}
+	// Only checked_in_at ever changes, so updates stay in place.
+	res, err := r.q.ExecContext(ctx,
+		`UPDATE device_assignments SET checked_in_at = ? WHERE assignment_id = ?`, checkedIn, a.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = r.q.ExecContext(ctx,
+		`INSERT INTO device_assignments (assignment_id, device_id, assignee, checked_out_at, due_at, checked_in_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		a.ID, a.DeviceID, a.Assignee, a.CheckedOutAt, a.DueAt, checkedIn)
+	return err
+}
+
+func (r *deviceRepo) OpenAssignment(ctx context.Context, deviceID string) (*device.Assignment, error) {
+	list, err := r.assignments(ctx, `WHERE device_id = ? AND checked_in_at IS NULL`, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("assignment", deviceID)
+	}
+	return list[0], nil
+}
+
+func (r *deviceRepo) Assignments(ctx context.Context, deviceID string) ([]*device.Assignment, error) {
+	return r.assignments(ctx, `WHERE device_id = ?`, deviceID)
+}
+
+func (r *deviceRepo) assignments(ctx context.Context, where string, args ...any) ([]*device.Assignment, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT assignment_id, device_id, assignee, checked_out_at, due_at, checked_in_at
+		FROM device_assignments `+where+` ORDER BY checked_out_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*device.Assignment
+	for rows.Next() {
+		var a device.Assignment
+		var checkedIn sql.NullTime
+		if err := rows.Scan(&a.ID, &a.DeviceID, &a.Assignee, &a.CheckedOutAt, &a.DueAt, &checkedIn); err != nil {
+			return nil, err
+		}
+		a.CheckedOutAt, a.DueAt = a.CheckedOutAt.UTC(), a.DueAt.UTC()
+		if checkedIn.Valid {
+			t := checkedIn.Time.UTC()
+			a.CheckedInAt = &t
+		}
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
+
+// overdueCondition matches devices whose open assignment is past due. Like
+// the tag conditions it binds id to the outer devices row.
+func overdueCondition(now time.Time) (string, []any) {
+	return `EXISTS (SELECT 1 FROM device_assignments WHERE device_id = id AND checked_in_at IS NULL AND due_at < ?)`, []any{now.UTC()}
+}
@@ -0,0 +1,42 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.AttributeSchemaStore = (*deviceRepo)(nil)
+
+func (r *deviceRepo) AttributeSchema(ctx context.Context, tenant string) ([]byte, error) {
+	var schema string
+	err := r.q.QueryRowContext(ctx,
+		`SELECT CAST(schema AS VARCHAR) FROM attribute_schemas WHERE tenant = ?`, tenant).Scan(&schema)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.NotFound("attribute schema", tenant)
+	}
+	if err != nil {
+		return nil, mapErr(ctx, err)
+	}
+	return []byte(schema), nil
+}
+
+func (r *deviceRepo) PutAttributeSchema(ctx context.Context, tenant string, schema []byte) error {
+	_, err := r.q.ExecContext(ctx,
+		`INSERT OR REPLACE INTO attribute_schemas (tenant, schema) VALUES (?, ?)`, tenant, string(schema))
+	return err
+}
+
+func (r *deviceRepo) DeleteAttributeSchema(ctx context.Context, tenant string) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM attribute_schemas WHERE tenant = ?`, tenant)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return repository.NotFound("attribute schema", tenant)
+	}
+	return nil
+}
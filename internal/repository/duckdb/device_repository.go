@@ -3,134 +3,596 @@ package duckdb
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
 
 	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
 	"github.com/leandronowras/device-api/internal/repository"
 )
 
+// querier is the subset of *sql.DB and *sql.Tx used by the repository, so the
+// same code path serves both plain calls and calls made inside WithTx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type deviceRepo struct {
 	db *sql.DB
+	q  querier
+	tx *sql.Tx
+
+	fts *ftsIndex
+	// txSem holds one token per running transaction, so they run one at a
+	// time; see WithTx. A channel rather than a mutex lets waiters give up
+	// when their context ends.
+	txSem chan struct{}
 }
 
 func NewDeviceRepository(db *sql.DB) repository.DeviceRepository {
-	repo := &deviceRepo{db: db}
+	repo := &deviceRepo{db: db, q: mappingQuerier{db}, fts: &ftsIndex{}, txSem: make(chan struct{}, 1)}
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS devices (
 		id TEXT PRIMARY KEY,
 		name TEXT NOT NULL,
 		brand TEXT NOT NULL,
 		state TEXT NOT NULL,
-		creation_time TIMESTAMP NOT NULL
+		creation_time TIMESTAMP NOT NULL,
+		attributes JSON,
+		serial_number TEXT
+	)`)
+	// Databases created before these columns existed get them added.
+	_, _ = db.Exec(`ALTER TABLE devices ADD COLUMN IF NOT EXISTS attributes JSON`)
+	_, _ = db.Exec(`ALTER TABLE devices ADD COLUMN IF NOT EXISTS serial_number TEXT`)
+	// device_serials enforces serial numbers unique per brand. It stands in
+	// for a unique index on devices, which would make DuckDB rewrite every
+	// update of those columns as delete+insert and trip the primary key.
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS device_serials (
+		brand_key TEXT NOT NULL,
+		serial_key TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		PRIMARY KEY (brand_key, serial_key)
+	)`)
+	// Tags live in a join table rather than a LIST column: DuckDB rewrites
+	// updated LIST values as delete+insert, which trips the devices primary
+	// key. There is no key here for the same reason; the domain keeps tags
+	// unique per device.
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS device_tags (
+		device_id TEXT NOT NULL,
+		tag TEXT NOT NULL
+	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS device_assignments (
+		assignment_id TEXT PRIMARY KEY,
+		device_id TEXT NOT NULL,
+		assignee TEXT NOT NULL,
+		checked_out_at TIMESTAMP NOT NULL,
+		due_at TIMESTAMP NOT NULL,
+		checked_in_at TIMESTAMP
+	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS reservations (
+		reservation_id TEXT PRIMARY KEY,
+		device_id TEXT NOT NULL,
+		reserver TEXT NOT NULL,
+		note TEXT,
+		starts_at TIMESTAMP NOT NULL,
+		ends_at TIMESTAMP NOT NULL,
+		status TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		cancelled_at TIMESTAMP
+	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS maintenance_tickets (
+		ticket_id TEXT PRIMARY KEY,
+		device_id TEXT NOT NULL,
+		description TEXT NOT NULL,
+		resolution TEXT,
+		cost DOUBLE,
+		previous_state TEXT NOT NULL,
+		opened_at TIMESTAMP NOT NULL,
+		closed_at TIMESTAMP
+	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+		webhook_id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
 	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_event_types (
+		webhook_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		PRIMARY KEY (webhook_id, event_type)
+	)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		delivery_id TEXT PRIMARY KEY,
+		webhook_id TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		payload JSON NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_status_code INTEGER,
+		last_error TEXT,
+		created_at TIMESTAMP NOT NULL,
+		delivered_at TIMESTAMP
+	)`)
+	_, _ = db.Exec(`CREATE SEQUENCE IF NOT EXISTS device_event_seq`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS device_events (
+		seq BIGINT PRIMARY KEY DEFAULT nextval('device_event_seq'),
+		type TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		payload JSON NOT NULL,
+		occurred_at TIMESTAMP NOT NULL
+	)`)
+	_, _ = db.Exec(`CREATE SEQUENCE IF NOT EXISTS outbox_seq`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS outbox (
+		seq BIGINT PRIMARY KEY DEFAULT nextval('outbox_seq'),
+		message_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		previous_state TEXT,
+		payload JSON NOT NULL,
+		domain_events JSON,
+		occurred_at TIMESTAMP NOT NULL,
+		dispatched_at TIMESTAMP
+	)`)
+	_, _ = db.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS domain_events JSON`)
+	_, _ = db.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS attempts INTEGER DEFAULT 0`)
+	_, _ = db.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS retry_at TIMESTAMP`)
+	_, _ = db.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS last_error TEXT`)
+	_, _ = db.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS attribute_schemas (
+		tenant TEXT PRIMARY KEY,
+		schema JSON NOT NULL
+	)`)
+	repo.fts.init(db)
 	return repo
 }
 
 func (r *deviceRepo) Save(ctx context.Context, d *device.Device) (*device.Device, error) {
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO devices (id, name, brand, state, creation_time) VALUES (?, ?, ?, ?, ?)`,
-		d.ID(), d.Name(), d.Brand(), d.State(), d.CreationTime())
+	attrs, err := encodeAttributes(d.Attributes())
+	if err != nil {
+		return nil, err
+	}
+	err = r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		if err := checkSerial(ctx, q, d); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO devices (id, name, brand, state, creation_time, attributes, serial_number) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			d.ID(), d.Name(), d.Brand(), d.State(), d.CreationTime(), attrs, nullIfEmpty(d.SerialNumber()))
+		if err != nil {
+			return err
+		}
+		if err := claimSerial(ctx, q, d); err != nil {
+			return err
+		}
+		if err := writeTags(ctx, q, d); err != nil {
+			return err
+		}
+		return recordChange(ctx, q, repository.EventCreated, d, "", d.PullEvents())
+	})
 	if err != nil {
 		return nil, err
 	}
+	r.fts.markDirty()
 	return d, nil
 }
 
 func (r *deviceRepo) FindByID(ctx context.Context, id string) (*device.Device, error) {
-	var idVal, name, brand, state string
-	var creationTime string
-
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, name, brand, state, creation_time FROM devices WHERE id = ?`, id).
-		Scan(&idVal, &name, &brand, &state, &creationTime)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, sql.ErrNoRows
-	}
+	var found *device.Device
+	err := r.each(ctx, `SELECT `+projection(nil, nil)+` FROM devices WHERE id = ?`, []any{id}, func(d *device.Device) error {
+		found = d
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	if found == nil {
+		return nil, repository.NotFound("device", id)
+	}
+	return found, nil
+}
 
-	ct, err := parseTime(creationTime)
+func (r *deviceRepo) FindAll(ctx context.Context, opts repository.ListOptions) ([]*device.Device, error) {
+	var list []*device.Device
+	err := r.ForEach(ctx, opts, func(d *device.Device) error {
+		list = append(list, d)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return device.NewWithID(idVal, name, brand, state, ct)
+	return list, nil
 }
 
-func (r *deviceRepo) FindAll(ctx context.Context, brand, state *string) ([]*device.Device, error) {
-	query := `SELECT id, name, brand, state, creation_time FROM devices`
-	clauses := []string{}
-	args := []any{}
+func (r *deviceRepo) ForEach(ctx context.Context, opts repository.ListOptions, fn func(d *device.Device) error) error {
+	where, args, err := compileFilter(opts.Filter)
+	if err != nil {
+		return err
+	}
+	if where != "" {
+		where = " WHERE " + where
+	}
 
-	if brand != nil && *brand != "" {
-		clauses = append(clauses, "LOWER(brand) = LOWER(?)")
-		args = append(args, *brand)
+	base := `SELECT *, 0.0 AS score FROM devices` + where
+	terms := searchTerms(opts.Search)
+	if len(terms) > 0 {
+		base, args = r.searchQuery(ctx, terms, where, args)
 	}
-	if state != nil && *state != "" {
-		clauses = append(clauses, "LOWER(state) = LOWER(?)")
-		args = append(args, *state)
+
+	keys := opts.Sort
+	byRelevance := len(terms) > 0 && len(keys) == 0
+	if len(keys) == 0 && !byRelevance {
+		keys = repository.DefaultSort
+	}
+
+	// ORDER BY resolves output aliases first, so every ordering column must
+	// be projected for real, including creation_time under relevance order.
+	projected := keys
+	if byRelevance {
+		projected = repository.DefaultSort
 	}
-	if len(clauses) > 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+	query := `SELECT ` + projection(opts.Fields, projected) + ` FROM (` + base + `) AS d`
+	if opts.After != nil {
+		if byRelevance {
+			return errors.New("cursor pagination needs an explicit sort when searching")
+		}
+		cond, condArgs, err := keysetCondition(keys, opts.After)
+		if err != nil {
+			return err
+		}
+		query += " WHERE " + cond
+		args = append(args, condArgs...)
+	}
+	order, err := orderBy(keys)
+	if err != nil {
+		return err
 	}
-	query += " ORDER BY creation_time DESC"
+	if byRelevance {
+		order = "score DESC, creation_time DESC, id"
+	}
+	query += " ORDER BY " + order
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+	if opts.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, opts.Offset)
+	}
+	return r.each(ctx, query, args, fn)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+// each runs a query selecting the columns listed by projection and hands
+// every row to fn as a domain device.
+func (r *deviceRepo) each(ctx context.Context, query string, args []any, fn func(d *device.Device) error) error {
+	rows, err := r.q.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var list []*device.Device
 	for rows.Next() {
-		var id, name, brand, state, creationTime string
-		if err := rows.Scan(&id, &name, &brand, &state, &creationTime); err != nil {
-			return nil, err
+		var id string
+		var name, brand, state, creationTime, attrs, tags, serial sql.NullString
+		if err := rows.Scan(&id, &name, &brand, &state, &creationTime, &attrs, &tags, &serial); err != nil {
+			return err
 		}
-		ct, err := parseTime(creationTime)
-		if err != nil {
-			return nil, err
+
+		// Columns left out of a projection come back NULL; such rows are
+		// rebuilt as read-only partial devices instead of being validated.
+		var ct time.Time
+		if creationTime.Valid {
+			if ct, err = parseTime(creationTime.String); err != nil {
+				return err
+			}
 		}
-		d, err := device.NewWithID(id, name, brand, state, ct)
-		if err != nil {
-			return nil, err
+		var d *device.Device
+		if name.Valid && brand.Valid && state.Valid && creationTime.Valid {
+			if d, err = device.Restore(id, name.String, brand.String, state.String, ct); err != nil {
+				return err
+			}
+		} else {
+			d = device.Rehydrate(id, name.String, brand.String, state.String, ct)
+		}
+		if attrs.Valid {
+			a, err := decodeAttributes(attrs.String)
+			if err != nil {
+				return err
+			}
+			d.SetAttributes(a)
+		}
+		if tags.Valid {
+			if err := d.SetTags(decodeTags(tags.String)); err != nil {
+				return err
+			}
+		}
+		if serial.Valid {
+			if err := d.SetSerialNumber(serial.String); err != nil {
+				return err
+			}
+		}
+		if err := fn(d); err != nil {
+			return err
 		}
-		list = append(list, d)
 	}
-	return list, rows.Err()
+	return mapErr(ctx, rows.Err())
 }
 
 func (r *deviceRepo) Update(ctx context.Context, d *device.Device) (*device.Device, error) {
-	res, err := r.db.ExecContext(ctx,
-		`UPDATE devices SET name = ?, brand = ?, state = ? WHERE id = ?`,
-		d.Name(), d.Brand(), d.State(), d.ID())
+	attrs, err := encodeAttributes(d.Attributes())
 	if err != nil {
 		return nil, err
 	}
+	err = r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		var previousState string
+		err := q.QueryRowContext(ctx, `SELECT state FROM devices WHERE id = ?`, d.ID()).Scan(&previousState)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.NotFound("device", d.ID())
+		}
+		if err != nil {
+			return mapErr(ctx, err)
+		}
+		if err := checkSerial(ctx, q, d); err != nil {
+			return err
+		}
+		res, err := q.ExecContext(ctx,
+			`UPDATE devices SET name = ?, brand = ?, state = ?, attributes = ?, serial_number = ? WHERE id = ?`,
+			d.Name(), d.Brand(), d.State(), attrs, nullIfEmpty(d.SerialNumber()), d.ID())
+		if err != nil {
+			return err
+		}
 
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return nil, sql.ErrNoRows
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			return repository.NotFound("device", d.ID())
+		}
+		if err := claimSerial(ctx, q, d); err != nil {
+			return err
+		}
+		if err := writeTags(ctx, q, d); err != nil {
+			return err
+		}
+		return recordChange(ctx, q, repository.EventUpdated, d, previousState, d.PullEvents())
+	})
+	if err != nil {
+		return nil, err
 	}
+	r.fts.markDirty()
 	return d, nil
 }
 
-func (r *deviceRepo) Delete(ctx context.Context, id string) error {
-	res, err := r.db.ExecContext(ctx, `DELETE FROM devices WHERE id = ?`, id)
+func (r *deviceRepo) Delete(ctx context.Context, d *device.Device) error {
+	id := d.ID()
+	err := r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		res, err := q.ExecContext(ctx, `DELETE FROM devices WHERE id = ?`, id)
+		if err != nil {
+			return mapErr(ctx, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return repository.NotFound("device", id)
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM device_serials WHERE device_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM device_assignments WHERE device_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM reservations WHERE device_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM maintenance_tickets WHERE device_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM device_tags WHERE device_id = ?`, id); err != nil {
+			return err
+		}
+		// The deleted event carries the device as it was.
+		return recordChange(ctx, q, repository.EventDeleted, d, "", d.PullEvents())
+	})
+	if err != nil {
+		return err
+	}
+	r.fts.markDirty()
+	return nil
+}
+
+func (r *deviceRepo) WithTx(ctx context.Context, fn func(tx repository.DeviceRepository) error) error {
+	// Already inside a transaction: join it instead of nesting.
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	// One transaction at a time. DuckDB would abort most concurrent writers
+	// on conflict anyway, and serializing them keeps event sequence numbers
+	// in commit order and makes check-then-insert rules (serial numbers,
+	// reservation overlaps) safe. Callers must keep slow work, such as
+	// reading a request body, out of fn: every writer waits for it.
+	select {
+	case r.txSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-r.txSem }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		return mapErr(ctx, err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&deviceRepo{db: r.db, q: mappingQuerier{tx}, tx: tx, fts: r.fts, txSem: r.txSem}); err != nil {
+		_ = tx.Rollback()
 		return err
 	}
+	return mapErr(ctx, tx.Commit())
+}
 
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return sql.ErrNoRows
+// projection lists the device columns in scan order, replacing the ones not
+// in fields with NULL. id and the sort keys are always selected because
+// paging needs them. An empty fields list selects everything.
+func projection(fields []string, keys []filter.SortKey) string {
+	cols := []string{"name", "brand", "state", "creation_time", "attributes", "tags", "serial_number"}
+	if len(fields) == 0 {
+		fields = cols
+	}
+	need := map[string]bool{}
+	for _, f := range fields {
+		need[f] = true
+	}
+	for _, k := range keys {
+		need[k.Field] = true
+	}
+	out := []string{"id"}
+	for _, c := range cols {
+		switch {
+		case c == "attributes" && need[c]:
+			// JSON values scan as maps; read the document as text instead.
+			out = append(out, "CAST(attributes AS VARCHAR) AS attributes")
+		case c == "tags" && need[c]:
+			out = append(out, tagsColumn+" AS tags")
+		case need[c]:
+			out = append(out, c)
+		default:
+			out = append(out, "NULL AS "+c)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+func encodeAttributes(a device.Attributes) (any, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func decodeAttributes(s string) (device.Attributes, error) {
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, err
+	}
+	return device.NormalizeAttributes(m)
+}
+
+// tagsColumn reads a device's tags as one comma-joined string; tags never
+// contain commas. device_tags has no id column, so id binds to the outer
+// devices row.
+const tagsColumn = `(SELECT string_agg(tag, ',' ORDER BY tag) FROM device_tags WHERE device_id = id)`
+
+// writeTags replaces the stored tags of d with its current set.
+func writeTags(ctx context.Context, q querier, d *device.Device) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM device_tags WHERE device_id = ?`, d.ID()); err != nil {
+		return err
+	}
+	for _, t := range d.Tags() {
+		if _, err := q.ExecContext(ctx, `INSERT INTO device_tags (device_id, tag) VALUES (?, ?)`, d.ID(), t); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// checkSerial fails with a conflict when another device of d's brand holds
+// d's serial number. Save and Update call it before writing the device row:
+// when they run inside a caller's transaction, such as an import, a row
+// written before the conflict was found would be committed with the rest.
+func checkSerial(ctx context.Context, q querier, d *device.Device) error {
+	if d.SerialNumber() == "" {
+		return nil
+	}
+	var owner string
+	err := q.QueryRowContext(ctx,
+		`SELECT device_id FROM device_serials WHERE brand_key = ? AND serial_key = ? AND device_id <> ?`,
+		strings.ToLower(d.Brand()), strings.ToLower(d.SerialNumber()), d.ID()).Scan(&owner)
+	switch {
+	case err == nil:
+		return serialConflict(d)
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	default:
+		return mapErr(ctx, err)
+	}
+}
+
+func serialConflict(d *device.Device) error {
+	return device.ErrConflict("serial_number",
+		"serial number "+d.SerialNumber()+" is already registered for brand "+d.Brand())
+}
+
+// claimSerial records d's serial number in device_serials, releasing the
+// one it held before. A serial already held by another device of the same
+// brand is a conflict; see checkSerial.
+func claimSerial(ctx context.Context, q querier, d *device.Device) error {
+	brandKey, serialKey := strings.ToLower(d.Brand()), strings.ToLower(d.SerialNumber())
+
+	var heldBrand, heldSerial string
+	err := q.QueryRowContext(ctx,
+		`SELECT brand_key, serial_key FROM device_serials WHERE device_id = ?`, d.ID()).Scan(&heldBrand, &heldSerial)
+	switch {
+	case err == nil && heldBrand == brandKey && heldSerial == serialKey:
+		return nil
+	case err == nil:
+		// Re-inserting the row just deleted would trip DuckDB's eager key
+		// check, which is why the unchanged case returns above.
+		if _, err := q.ExecContext(ctx, `DELETE FROM device_serials WHERE device_id = ?`, d.ID()); err != nil {
+			return err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return mapErr(ctx, err)
+	}
+	if serialKey == "" {
+		return nil
+	}
+
+	conflict := serialConflict(d)
+	var owner string
+	err = q.QueryRowContext(ctx,
+		`SELECT device_id FROM device_serials WHERE brand_key = ? AND serial_key = ?`, brandKey, serialKey).Scan(&owner)
+	if err == nil {
+		return conflict
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return mapErr(ctx, err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO device_serials (brand_key, serial_key, device_id) VALUES (?, ?, ?)`, brandKey, serialKey, d.ID())
+	if errors.Is(err, repository.ErrConflict) {
+		// A concurrent transaction claimed it after our check.
+		return conflict
+	}
+	return err
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func decodeTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
 func parseTime(s string) (time.Time, error) {
 	layouts := []string{
 		time.RFC3339,
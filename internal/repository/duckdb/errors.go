@@ -0,0 +1,50 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/marcboeker/go-duckdb"
+)
+
+// mapErr translates driver errors into the repository's: constraint
+// violations become a repository.ConflictError and statements interrupted
+// by a cancelled context report the context's error. Anything else passes
+// through unchanged.
+func mapErr(ctx context.Context, err error) error {
+	var derr *duckdb.Error
+	if err == nil || !errors.As(err, &derr) {
+		return err
+	}
+	switch {
+	case derr.Type == duckdb.ErrorTypeConstraint:
+		return &repository.ConflictError{Err: err}
+	case derr.Type == duckdb.ErrorTypeInterrupt && ctx.Err() != nil:
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return err
+}
+
+// mappingQuerier runs every statement through mapErr. Errors from
+// QueryRowContext surface at Scan, so those call sites map them
+// themselves.
+type mappingQuerier struct {
+	q querier
+}
+
+func (m mappingQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := m.q.ExecContext(ctx, query, args...)
+	return res, mapErr(ctx, err)
+}
+
+func (m mappingQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := m.q.QueryContext(ctx, query, args...)
+	return rows, mapErr(ctx, err)
+}
+
+func (m mappingQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return m.q.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,137 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+func openRepo(t *testing.T) repository.DeviceRepository {
+	t.Helper()
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return NewDeviceRepository(db)
+}
+
+func TestUnknownRecordsAreNotFound(t *testing.T) {
+	repo := openRepo(t)
+	ctx := context.Background()
+
+	_, err := repo.FindByID(ctx, "missing")
+	var nf *repository.NotFoundError
+	if !errors.As(err, &nf) || nf.Resource != "device" || nf.ID != "missing" {
+		t.Errorf("FindByID: got %v", err)
+	}
+	d, _ := device.New("Phone", "Acme")
+	if err := repo.Delete(ctx, d); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete: got %v", err)
+	}
+	if _, err := repo.Update(ctx, d); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update: got %v", err)
+	}
+	now := time.Now()
+	res, err := device.NewReservation(d.ID(), "qa", "", now.Add(time.Hour), now.Add(2*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.(repository.ReservationStore).CreateReservation(ctx, res); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("CreateReservation: got %v", err)
+	}
+}
+
+func TestConstraintViolationsAreConflicts(t *testing.T) {
+	repo := openRepo(t)
+	ctx := context.Background()
+
+	d, _ := device.New("Phone", "Acme")
+	if _, err := repo.Save(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	_, err := repo.Save(ctx, d)
+	if !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("saving a duplicate id: got %v", err)
+	}
+}
+
+func TestSerialConflictsInsideATransactionWriteNothing(t *testing.T) {
+	repo := openRepo(t)
+	ctx := context.Background()
+
+	a, _ := device.New("Phone", "Apple")
+	_ = a.SetSerialNumber("SN1")
+	b, _ := device.New("Phone", "Apple")
+	_ = b.SetSerialNumber("sn1")
+	c, _ := device.New("Phone", "Apple")
+	_ = c.SetSerialNumber("SN2")
+	err := repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		if _, err := tx.Save(ctx, a); err != nil {
+			return err
+		}
+		if _, err := tx.Save(ctx, c); err != nil {
+			return err
+		}
+		var derr *device.DomainError
+		if _, err := tx.Save(ctx, b); !errors.As(err, &derr) || derr.Code != "conflict_serial_number" {
+			t.Errorf("Save: got %v", err)
+		}
+		_ = c.SetSerialNumber("SN1")
+		if _, err := tx.Update(ctx, c); !errors.As(err, &derr) || derr.Code != "conflict_serial_number" {
+			t.Errorf("Update: got %v", err)
+		}
+		return nil // commit what succeeded, as an import does
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.FindByID(ctx, b.ID()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("conflicting device was stored: %v", err)
+	}
+	if got, err := repo.FindByID(ctx, c.ID()); err != nil || got.SerialNumber() != "SN2" {
+		t.Errorf("conflicting update was stored: %v, %v", got, err)
+	}
+}
+
+func TestCancelledContextsReportTheContextError(t *testing.T) {
+	repo := openRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.FindAll(ctx, repository.ListOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestWaitingForATransactionEndsWithTheContext(t *testing.T) {
+	repo := openRepo(t)
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = repo.WithTx(context.Background(), func(repository.DeviceRepository) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := repo.WithTx(ctx, func(repository.DeviceRepository) error {
+		t.Error("ran while another transaction held the lock")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
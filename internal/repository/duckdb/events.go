@@ -0,0 +1,98 @@
+package duckdb
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.EventLog = (*deviceRepo)(nil)
+
+// recordChange logs a change to d in the event log and the outbox; callers
+// run it in the transaction that makes the change, so a change is never
+// committed without its event. previousState is only set for updates;
+// events are the domain events behind the change.
+func recordChange(ctx context.Context, q querier, typ string, d *device.Device, previousState string, events []device.Event) error {
+	payload, err := json.Marshal(d.Snapshot())
+	if err != nil {
+		return err
+	}
+	type namedEvent struct {
+		Type string       `json:"type"`
+		Data device.Event `json:"data"`
+	}
+	named := make([]namedEvent, 0, len(events))
+	for _, e := range events {
+		named = append(named, namedEvent{Type: e.EventName(), Data: e})
+	}
+	domainEvents, err := json.Marshal(named)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if _, err := q.ExecContext(ctx,
+		`INSERT INTO device_events (type, device_id, payload, occurred_at) VALUES (?, ?, ?, ?)`,
+		typ, d.ID(), string(payload), now); err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO outbox (message_id, type, device_id, previous_state, payload, domain_events, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), typ, d.ID(), nullIfEmpty(previousState), string(payload), string(domainEvents), now)
+	return err
+}
+
+func (r *deviceRepo) EventsAfter(ctx context.Context, after int64, limit int) ([]repository.Event, error) {
+	return r.events(ctx, `WHERE seq > ? ORDER BY seq LIMIT ?`, after, limit)
+}
+
+func (r *deviceRepo) DeviceHistory(ctx context.Context, deviceID string, limit int) ([]repository.Event, error) {
+	return r.events(ctx, `WHERE device_id = ? ORDER BY seq DESC LIMIT ?`, deviceID, limit)
+}
+
+func (r *deviceRepo) events(ctx context.Context, where string, args ...any) ([]repository.Event, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT seq, type, device_id, CAST(payload AS VARCHAR), occurred_at
+		FROM device_events `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []repository.Event
+	for rows.Next() {
+		var e repository.Event
+		var payload string
+		if err := rows.Scan(&e.Seq, &e.Type, &e.DeviceID, &payload, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		e.OccurredAt = e.OccurredAt.UTC()
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func (r *deviceRepo) PruneEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	// Keeping each live device's latest event anchors its current stay for
+	// the time-in-state statistics.
+	res, err := r.q.ExecContext(ctx, `
+		DELETE FROM device_events
+		WHERE occurred_at < ? AND seq NOT IN (
+			SELECT MAX(e.seq) FROM device_events e JOIN devices d ON d.id = e.device_id GROUP BY e.device_id
+		)`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *deviceRepo) LastEventSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := r.q.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM device_events`).Scan(&seq)
+	return seq, err
+}
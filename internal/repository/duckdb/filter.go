@@ -0,0 +1,206 @@
+package duckdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/filter"
+)
+
+// filterColumns maps filter fields to SQL expressions. Text columns other
+// than id compare case-insensitively, matching the brand/state query params.
+var filterColumns = map[string]struct {
+	expr     string
+	foldCase bool
+}{
+	"id":            {expr: "id"},
+	"name":          {expr: "name", foldCase: true},
+	"brand":         {expr: "brand", foldCase: true},
+	"state":         {expr: "state", foldCase: true},
+	"serial_number": {expr: "serial_number", foldCase: true},
+	"creation_time": {expr: "creation_time"},
+}
+
+// compileFilter turns a parsed filter into a parameterized SQL condition.
+// A nil expression compiles to "".
+func compileFilter(e filter.Expr) (string, []any, error) {
+	if e == nil {
+		return "", nil, nil
+	}
+	var args []any
+	sql, err := compileExpr(e, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func compileExpr(e filter.Expr, args *[]any) (string, error) {
+	switch n := e.(type) {
+	case filter.And:
+		return compileJoin(n.Exprs, " AND ", args)
+	case filter.Or:
+		return compileJoin(n.Exprs, " OR ", args)
+	case filter.Not:
+		inner, err := compileExpr(n.Expr, args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	case filter.Comparison:
+		return compileComparison(n, args)
+	default:
+		return "", fmt.Errorf("filter: unsupported node %T", e)
+	}
+}
+
+func compileJoin(exprs []filter.Expr, sep string, args *[]any) (string, error) {
+	parts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		p, err := compileExpr(e, args)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "("+p+")")
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func compileComparison(c filter.Comparison, args *[]any) (string, error) {
+	if key, ok := strings.CutPrefix(c.Field, filter.AttrPrefix); ok {
+		return compileAttrComparison(key, c, args)
+	}
+	if c.Field == "tag" {
+		return compileTagComparison(c, args)
+	}
+	if c.Field == "overdue" {
+		return compileOverdueComparison(c, args)
+	}
+	col, ok := filterColumns[c.Field]
+	if !ok {
+		return "", fmt.Errorf("filter: no column for field %q", c.Field)
+	}
+	lhs, ph := col.expr, "?"
+	if col.foldCase {
+		lhs, ph = "LOWER("+col.expr+")", "LOWER(?)"
+	}
+
+	switch c.Op {
+	case filter.OpEq, filter.OpNe, filter.OpGt, filter.OpGe, filter.OpLt, filter.OpLe:
+		*args = append(*args, c.Values[0])
+		op := string(c.Op)
+		if c.Op == filter.OpNe {
+			op = "<>"
+		}
+		return lhs + " " + op + " " + ph, nil
+	case filter.OpContains:
+		*args = append(*args, likeContains(c.Values[0]))
+		return col.expr + ` ILIKE ? ESCAPE '\'`, nil
+	case filter.OpIn:
+		phs := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			phs[i] = ph
+			*args = append(*args, v)
+		}
+		return lhs + " IN (" + strings.Join(phs, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("filter: unsupported operator %q", c.Op)
+	}
+}
+
+// compileAttrComparison compares a custom attribute stored in the attributes
+// JSON document. Ordering operators compare numerically when the operand is a
+// number and as text otherwise (RFC 3339 times sort correctly as text).
+func compileAttrComparison(key string, c filter.Comparison, args *[]any) (string, error) {
+	*args = append(*args, "$."+key)
+	lhs := "json_extract_string(attributes, ?)"
+
+	switch c.Op {
+	case filter.OpEq, filter.OpNe:
+		op := "="
+		if c.Op == filter.OpNe {
+			op = "<>"
+		}
+		v := fmt.Sprint(c.Values[0])
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			*args = append(*args, v)
+			return "LOWER(" + lhs + ") " + op + " LOWER(?)", nil
+		}
+		// A number matches stored numbers by value, so 8 matches 8.0, but
+		// strings as written: "17.10" is not 17.1.
+		path := "$." + key
+		*args = append(*args, path, f, path, v)
+		return "(CASE WHEN json_type(attributes, ?) IN ('BIGINT', 'UBIGINT', 'DOUBLE')" +
+			" THEN TRY_CAST(" + lhs + " AS DOUBLE) " + op + " ?" +
+			" ELSE LOWER(json_extract_string(attributes, ?)) " + op + " LOWER(?) END)", nil
+	case filter.OpGt, filter.OpGe, filter.OpLt, filter.OpLe:
+		v := fmt.Sprint(c.Values[0])
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			*args = append(*args, f)
+			return "TRY_CAST(" + lhs + " AS DOUBLE) " + string(c.Op) + " ?", nil
+		}
+		*args = append(*args, v)
+		return lhs + " " + string(c.Op) + " ?", nil
+	case filter.OpContains:
+		*args = append(*args, likeContains(c.Values[0]))
+		return lhs + ` ILIKE ? ESCAPE '\'`, nil
+	case filter.OpIn:
+		phs := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			phs[i] = "LOWER(?)"
+			*args = append(*args, v)
+		}
+		return "LOWER(" + lhs + ") IN (" + strings.Join(phs, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("filter: unsupported operator %q", c.Op)
+	}
+}
+
+// likeContains builds a substring LIKE pattern, escaping wildcards so "~" is
+// a plain substring match.
+func likeContains(v any) string {
+	esc := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(fmt.Sprint(v))
+	return "%" + esc + "%"
+}
+
+// compileTagComparison tests membership in device_tags; id binds to the
+// outer devices row.
+func compileTagComparison(c filter.Comparison, args *[]any) (string, error) {
+	const has = "EXISTS (SELECT 1 FROM device_tags WHERE device_id = id AND tag "
+	switch c.Op {
+	case filter.OpEq:
+		*args = append(*args, c.Values[0])
+		return has + "= ?)", nil
+	case filter.OpNe:
+		*args = append(*args, c.Values[0])
+		return "NOT " + has + "= ?)", nil
+	case filter.OpIn:
+		marks := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			marks[i] = "?"
+			*args = append(*args, v)
+		}
+		return has + "IN (" + strings.Join(marks, ", ") + "))", nil
+	default:
+		return "", fmt.Errorf("filter: unsupported operator %q for tag", c.Op)
+	}
+}
+
+func compileOverdueComparison(c filter.Comparison, args *[]any) (string, error) {
+	want, ok := c.Values[0].(bool)
+	if !ok {
+		return "", fmt.Errorf("filter: overdue needs a boolean, got %v", c.Values[0])
+	}
+	if c.Op == filter.OpNe {
+		want = !want
+	}
+	cond, condArgs := overdueCondition(time.Now())
+	*args = append(*args, condArgs...)
+	if !want {
+		return "NOT " + cond, nil
+	}
+	return cond, nil
+}
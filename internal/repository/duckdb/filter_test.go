@@ -0,0 +1,53 @@
+package duckdb
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+func TestAttributeEqualityComparesStringsAsWritten(t *testing.T) {
+	repo := openRepo(t)
+	ctx := context.Background()
+	for name, attrs := range map[string]device.Attributes{
+		"a": {"os_version": "17.1", "ram_gb": float64(8)},
+		"b": {"os_version": "17.10", "ram_gb": 8.5},
+		"c": {"os_version": "1.0", "ram_gb": "8"},
+	} {
+		d, _ := device.New(name, "Acme")
+		d.SetAttributes(attrs)
+		if _, err := repo.Save(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		filter filter.Expr
+		want   string
+	}{
+		{filter.Eq("attr.os_version", "17.1"), "a"},
+		{filter.Eq("attr.os_version", "1"), ""},
+		{filter.Eq("attr.ram_gb", "8"), "a,c"},
+		{filter.Eq("attr.ram_gb", "8.0"), "a"},
+		{filter.Comparison{Field: "attr.os_version", Op: filter.OpNe, Values: []any{"17.1"}}, "b,c"},
+	}
+	for _, c := range cases {
+		list, err := repo.FindAll(ctx, repository.ListOptions{Filter: c.filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, d := range list {
+			names = append(names, d.Name())
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != c.want {
+			t.Errorf("%+v matched %q, want %q", c.filter, got, c.want)
+		}
+	}
+}
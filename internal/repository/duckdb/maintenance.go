@@ -0,0 +1,81 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.MaintenanceStore = (*deviceRepo)(nil)
+
+func (r *deviceRepo) SaveTicket(ctx context.Context, t *device.MaintenanceTicket) error {
+	var cost any
+	if t.Cost != nil {
+		cost = *t.Cost
+	}
+	// Closing touches only non-key columns, so the update stays in place.
+	res, err := r.q.ExecContext(ctx,
+		`UPDATE maintenance_tickets SET resolution = ?, cost = ?, closed_at = ? WHERE ticket_id = ?`,
+		nullIfEmpty(t.Resolution), cost, timeOrNil(t.ClosedAt), t.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = r.q.ExecContext(ctx,
+		`INSERT INTO maintenance_tickets (ticket_id, device_id, description, resolution, cost, previous_state, opened_at, closed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.DeviceID, t.Description, nullIfEmpty(t.Resolution), cost, t.PreviousState, t.OpenedAt, timeOrNil(t.ClosedAt))
+	return err
+}
+
+func (r *deviceRepo) Ticket(ctx context.Context, deviceID, ticketID string) (*device.MaintenanceTicket, error) {
+	list, err := r.tickets(ctx, `WHERE device_id = ? AND ticket_id = ?`, deviceID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("maintenance ticket", ticketID)
+	}
+	return list[0], nil
+}
+
+func (r *deviceRepo) Tickets(ctx context.Context, deviceID string) ([]*device.MaintenanceTicket, error) {
+	return r.tickets(ctx, `WHERE device_id = ?`, deviceID)
+}
+
+func (r *deviceRepo) tickets(ctx context.Context, where string, args ...any) ([]*device.MaintenanceTicket, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT ticket_id, device_id, description, resolution, cost, previous_state, opened_at, closed_at
+		FROM maintenance_tickets `+where+` ORDER BY opened_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*device.MaintenanceTicket
+	for rows.Next() {
+		var t device.MaintenanceTicket
+		var resolution sql.NullString
+		var cost sql.NullFloat64
+		var closed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.DeviceID, &t.Description, &resolution, &cost, &t.PreviousState, &t.OpenedAt, &closed); err != nil {
+			return nil, err
+		}
+		t.Resolution = resolution.String
+		if cost.Valid {
+			c := cost.Float64
+			t.Cost = &c
+		}
+		t.OpenedAt = t.OpenedAt.UTC()
+		if closed.Valid {
+			c := closed.Time.UTC()
+			t.ClosedAt = &c
+		}
+		list = append(list, &t)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,69 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.Outbox = (*deviceRepo)(nil)
+
+func (r *deviceRepo) PendingOutbox(ctx context.Context, limit int) ([]repository.OutboxMessage, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT seq, message_id, type, device_id, previous_state, CAST(payload AS VARCHAR),
+			COALESCE(CAST(domain_events AS VARCHAR), '[]'), occurred_at, COALESCE(attempts, 0), retry_at
+		FROM outbox WHERE dispatched_at IS NULL AND dead_at IS NULL ORDER BY seq LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []repository.OutboxMessage
+	for rows.Next() {
+		var m repository.OutboxMessage
+		var previous sql.NullString
+		var payload, domainEvents string
+		var retryAt sql.NullTime
+		if err := rows.Scan(&m.Seq, &m.ID, &m.Type, &m.DeviceID, &previous, &payload, &domainEvents, &m.OccurredAt, &m.Attempts, &retryAt); err != nil {
+			return nil, err
+		}
+		if retryAt.Valid {
+			m.RetryAt = retryAt.Time.UTC()
+		}
+		m.PreviousState = previous.String
+		m.Payload = []byte(payload)
+		m.DomainEvents = []byte(domainEvents)
+		m.OccurredAt = m.OccurredAt.UTC()
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+func (r *deviceRepo) MarkDispatched(ctx context.Context, seq int64, at time.Time) error {
+	_, err := r.q.ExecContext(ctx, `UPDATE outbox SET dispatched_at = ? WHERE seq = ?`, at.UTC(), seq)
+	return err
+}
+
+func (r *deviceRepo) MarkFailed(ctx context.Context, seq int64, reason string, retryAt time.Time, dead bool) error {
+	if len(reason) > 500 {
+		reason = reason[:500]
+	}
+	var deadAt any
+	if dead {
+		deadAt = time.Now().UTC()
+	}
+	_, err := r.q.ExecContext(ctx,
+		`UPDATE outbox SET attempts = COALESCE(attempts, 0) + 1, last_error = ?, retry_at = ?, dead_at = ? WHERE seq = ?`,
+		reason, retryAt.UTC(), deadAt, seq)
+	return err
+}
+
+func (r *deviceRepo) PruneOutbox(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM outbox WHERE dispatched_at < ?`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,137 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.ParquetTransfer = (*deviceRepo)(nil)
+
+// errParquetUnreadable is returned when DuckDB cannot open the uploaded file.
+var errParquetUnreadable = &device.DomainError{
+	Code: "invalid_parquet", Field: "body", Message: "file is not a readable Parquet file with device columns", HTTP: http.StatusBadRequest,
+}
+
+func (r *deviceRepo) ExportParquet(ctx context.Context, path string) error {
+	// COPY does not accept bound parameters for the target, so the path is
+	// embedded as an escaped string literal.
+	_, err := r.q.ExecContext(ctx,
+		`COPY (SELECT id, name, brand, state, creation_time, CAST(attributes AS VARCHAR) AS attributes, `+
+			`string_split(`+tagsColumn+`, ',') AS tags, serial_number FROM devices ORDER BY creation_time DESC) TO `+
+			quoteLiteral(path)+` (FORMAT PARQUET)`)
+	return err
+}
+
+func (r *deviceRepo) ImportParquet(ctx context.Context, path string) (int, error) {
+	// Files exported by older versions lack the newer columns.
+	optional := map[string]string{
+		"attributes":    "CAST(attributes AS TEXT)",
+		"tags":          "array_to_string(tags, ',')",
+		"serial_number": "CAST(serial_number AS TEXT)",
+	}
+	cols := map[string]string{}
+	for name, expr := range optional {
+		var n int
+		err := r.q.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM (DESCRIBE SELECT * FROM read_parquet(`+quoteLiteral(path)+`)) WHERE column_name = ?`, name).Scan(&n)
+		if err != nil {
+			return 0, errParquetUnreadable
+		}
+		cols[name] = "NULL"
+		if n > 0 {
+			cols[name] = expr
+		}
+	}
+
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT CAST(id AS TEXT), CAST(name AS TEXT), CAST(brand AS TEXT), CAST(state AS TEXT), CAST(creation_time AS TIMESTAMP), `+
+			cols["attributes"]+`, `+cols["tags"]+`, `+cols["serial_number"]+` FROM read_parquet(`+quoteLiteral(path)+`)`)
+	if err != nil {
+		return 0, errParquetUnreadable
+	}
+
+	// Run every row through the domain constructor before touching the table.
+	var list []*device.Device
+	for rows.Next() {
+		var id, name, brand, state, attrs, tags, serial sql.NullString
+		var creationTime sql.NullTime
+		if err := rows.Scan(&id, &name, &brand, &state, &creationTime, &attrs, &tags, &serial); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ct := creationTime.Time
+		if !creationTime.Valid {
+			ct = time.Now().UTC()
+		}
+		st := state.String
+		if strings.TrimSpace(st) == "" {
+			st = device.StateAvailable
+		}
+		if strings.TrimSpace(id.String) == "" {
+			rows.Close()
+			return 0, device.ErrRequired("id")
+		}
+		d, err := device.NewWithID(id.String, name.String, brand.String, st, ct)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if attrs.Valid {
+			a, err := decodeAttributes(attrs.String)
+			if err != nil {
+				rows.Close()
+				return 0, device.ErrInvalid("attributes", "device "+id.String+" has invalid attributes", http.StatusBadRequest)
+			}
+			d.SetAttributes(a)
+		}
+		if tags.Valid {
+			if err := d.SetTags(decodeTags(tags.String)); err != nil {
+				rows.Close()
+				return 0, err
+			}
+		}
+		if serial.Valid {
+			if err := d.SetSerialNumber(serial.String); err != nil {
+				rows.Close()
+				return 0, err
+			}
+		}
+		list = append(list, d)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		for _, d := range list {
+			_, err := tx.FindByID(ctx, d.ID())
+			if err == nil {
+				return device.ErrConflict("device", "device "+d.ID()+" already exists")
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			if _, err := tx.Save(ctx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
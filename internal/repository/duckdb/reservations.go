@@ -0,0 +1,149 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.ReservationStore = (*deviceRepo)(nil)
+
+const reservationColumns = `reservation_id, device_id, reserver, note, starts_at, ends_at, status, created_at, cancelled_at`
+
+func (r *deviceRepo) CreateReservation(ctx context.Context, res *device.Reservation) error {
+	// DuckDB has no exclusion constraints; the checks below are safe because
+	// WithTx runs transactions one at a time, so the device cannot be
+	// deleted or booked in between.
+	return r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		var exists int
+		err := q.QueryRowContext(ctx, `SELECT 1 FROM devices WHERE id = ?`, res.DeviceID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.NotFound("device", res.DeviceID)
+		}
+		if err != nil {
+			return mapErr(ctx, err)
+		}
+		var clash sql.NullString
+		err = q.QueryRowContext(ctx,
+			`SELECT reservation_id FROM reservations
+			WHERE device_id = ? AND status IN (?, ?) AND starts_at < ? AND ends_at > ?
+			LIMIT 1`,
+			res.DeviceID, device.ReservationScheduled, device.ReservationActive, res.EndsAt, res.StartsAt).Scan(&clash)
+		if err == nil {
+			return device.ErrConflict("reservation", "device is already reserved in that period (reservation "+clash.String+")")
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return mapErr(ctx, err)
+		}
+		_, err = q.ExecContext(ctx,
+			`INSERT INTO reservations (`+reservationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			res.ID, res.DeviceID, res.Reserver, nullIfEmpty(res.Note), res.StartsAt, res.EndsAt, res.Status, res.CreatedAt, timeOrNil(res.CancelledAt))
+		return err
+	})
+}
+
+func (r *deviceRepo) UpdateReservation(ctx context.Context, res *device.Reservation) error {
+	result, err := r.q.ExecContext(ctx,
+		`UPDATE reservations SET status = ?, cancelled_at = ? WHERE reservation_id = ?`,
+		res.Status, timeOrNil(res.CancelledAt), res.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return repository.NotFound("reservation", res.ID)
+	}
+	return nil
+}
+
+func (r *deviceRepo) Reservation(ctx context.Context, id string) (*device.Reservation, error) {
+	list, err := r.reservations(ctx, `WHERE reservation_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("reservation", id)
+	}
+	return list[0], nil
+}
+
+func (r *deviceRepo) Reservations(ctx context.Context, rq repository.ReservationQuery) ([]*device.Reservation, error) {
+	var conds []string
+	var args []any
+	if rq.DeviceID != "" {
+		conds = append(conds, "device_id = ?")
+		args = append(args, rq.DeviceID)
+	}
+	if rq.Reserver != "" {
+		conds = append(conds, "reserver = ?")
+		args = append(args, rq.Reserver)
+	}
+	if len(rq.Statuses) > 0 {
+		marks := make([]string, len(rq.Statuses))
+		for i, s := range rq.Statuses {
+			marks[i] = "?"
+			args = append(args, s)
+		}
+		conds = append(conds, "status IN ("+strings.Join(marks, ", ")+")")
+	}
+	if !rq.To.IsZero() {
+		conds = append(conds, "starts_at < ?")
+		args = append(args, rq.To.UTC())
+	}
+	if !rq.From.IsZero() {
+		conds = append(conds, "ends_at > ?")
+		args = append(args, rq.From.UTC())
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	return r.reservations(ctx, where, args...)
+}
+
+func (r *deviceRepo) DueReservations(ctx context.Context, now time.Time) ([]*device.Reservation, error) {
+	now = now.UTC()
+	return r.reservations(ctx,
+		`WHERE (status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?)`,
+		device.ReservationScheduled, now, device.ReservationActive, now)
+}
+
+func (r *deviceRepo) reservations(ctx context.Context, where string, args ...any) ([]*device.Reservation, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT `+reservationColumns+` FROM reservations `+where+` ORDER BY starts_at, reservation_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*device.Reservation
+	for rows.Next() {
+		var res device.Reservation
+		var note sql.NullString
+		var cancelled sql.NullTime
+		if err := rows.Scan(&res.ID, &res.DeviceID, &res.Reserver, &note, &res.StartsAt, &res.EndsAt,
+			&res.Status, &res.CreatedAt, &cancelled); err != nil {
+			return nil, err
+		}
+		res.Note = note.String
+		res.StartsAt, res.EndsAt, res.CreatedAt = res.StartsAt.UTC(), res.EndsAt.UTC(), res.CreatedAt.UTC()
+		if cancelled.Valid {
+			t := cancelled.Time.UTC()
+			res.CancelledAt = &t
+		}
+		list = append(list, &res)
+	}
+	return list, rows.Err()
+}
+
+func timeOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
@@ -0,0 +1,138 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ftsRefreshInterval bounds how often writes can trigger a rebuild of the
+// full-text index. DuckDB can only rebuild it whole, which costs a scan of
+// the devices table, so under steady writes searches pay for at most one
+// rebuild per interval and otherwise rank with a slightly stale index.
+// Staleness only affects the BM25 boost: matching always reads the table.
+const ftsRefreshInterval = 30 * time.Second
+
+// ftsIndex tracks DuckDB's full-text search extension. When the extension can
+// be loaded, a BM25 index over name and brand is rebuilt lazily on a search
+// after a write, at most once per ftsRefreshInterval, and used to boost
+// relevance. Without it, search falls back to the word-level scoring alone.
+type ftsIndex struct {
+	available   bool
+	dirty       atomic.Bool
+	mu          sync.Mutex
+	built       bool      // guarded by mu
+	lastAttempt time.Time // guarded by mu
+	now         func() time.Time
+}
+
+func (f *ftsIndex) init(db *sql.DB) {
+	// LOAD only: installing would reach out to the network on every start.
+	if _, err := db.Exec(`LOAD fts`); err == nil {
+		f.available = true
+		f.dirty.Store(true)
+	}
+}
+
+func (f *ftsIndex) markDirty() {
+	if f != nil && f.available {
+		f.dirty.Store(true)
+	}
+}
+
+// refresh rebuilds the index when it is stale and the last attempt is old
+// enough, and reports whether searches can use it. A failed rebuild may have
+// dropped the old index, so it is not used until a rebuild succeeds.
+func (f *ftsIndex) refresh(ctx context.Context, q querier) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if f.now != nil {
+		now = f.now()
+	}
+	if !f.dirty.Load() || (!f.lastAttempt.IsZero() && now.Sub(f.lastAttempt) < ftsRefreshInterval) {
+		return f.built
+	}
+	f.dirty.Store(false)
+	f.lastAttempt = now
+	_, err := q.ExecContext(ctx, `PRAGMA create_fts_index('devices', 'id', 'name', 'brand', overwrite=1)`)
+	if err != nil {
+		f.dirty.Store(true)
+	}
+	f.built = err == nil
+	return f.built
+}
+
+type searchTerm struct {
+	text       string
+	prefixOnly bool
+}
+
+// searchTerms splits free text into lower-cased terms. A term ending in "*"
+// matches word prefixes only.
+func searchTerms(q string) []searchTerm {
+	var terms []searchTerm
+	for _, t := range strings.Fields(strings.ToLower(q)) {
+		prefixOnly := strings.HasSuffix(t, "*")
+		t = strings.TrimRight(t, "*")
+		if t != "" {
+			terms = append(terms, searchTerm{text: t, prefixOnly: prefixOnly})
+		}
+	}
+	return terms
+}
+
+// searchQuery selects devices whose name or brand match every term, with a
+// relevance score column. Terms match
+// exactly, as a word prefix, or fuzzily (Jaro-Winkler), in decreasing order
+// of weight; prefix-only terms skip the exact and fuzzy cases. where is an
+// already compiled " WHERE ..." clause (or "") over the devices table. The
+// caller wraps the result to apply ordering and paging.
+func (r *deviceRepo) searchQuery(ctx context.Context, terms []searchTerm, where string, whereArgs []any) (string, []any) {
+	values := make([]string, 0, len(terms))
+	args := make([]any, 0, len(terms)*2+len(whereArgs)+1)
+	texts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		values = append(values, "(?, ?)")
+		args = append(args, t.text, t.prefixOnly)
+		texts = append(texts, t.text)
+	}
+	args = append(args, whereArgs...)
+
+	bm25 := "0"
+	if r.fts.available && r.tx == nil {
+		if r.fts.refresh(ctx, r.db) {
+			bm25 = "COALESCE(fts_main_devices.match_bm25(d.id, ?), 0)"
+			args = append(args, strings.Join(texts, " "))
+		}
+	}
+
+	query := `
+		WITH terms(term, prefix_only) AS (VALUES ` + strings.Join(values, ", ") + `),
+		words AS (
+			SELECT id, unnest(string_split(lower(name || ' ' || brand), ' ')) AS word
+			FROM devices` + where + `
+		),
+		term_scores AS (
+			SELECT w.id, t.term, MAX(CASE
+				WHEN NOT t.prefix_only AND w.word = t.term THEN 3.0
+				WHEN starts_with(w.word, t.term) THEN 2.0
+				WHEN NOT t.prefix_only AND length(t.term) >= 3
+					AND jaro_winkler_similarity(w.word, t.term) >= 0.85 THEN jaro_winkler_similarity(w.word, t.term)
+				ELSE 0.0 END) AS score
+			FROM words w CROSS JOIN terms t
+			GROUP BY w.id, t.term
+		),
+		matches AS (
+			SELECT id, SUM(score) AS score
+			FROM term_scores
+			GROUP BY id
+			HAVING MIN(score) > 0
+		)
+		SELECT d.*, m.score + ` + bm25 + ` AS score
+		FROM matches m JOIN devices d ON d.id = m.id`
+	return query, args
+}
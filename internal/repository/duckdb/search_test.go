@@ -0,0 +1,47 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// countingExec counts index rebuilds; only ExecContext is used.
+type countingExec struct {
+	querier
+	n int
+}
+
+func (c *countingExec) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	c.n++
+	return nil, nil
+}
+
+func TestFTSRebuildsAtMostOncePerInterval(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := &ftsIndex{available: true, now: func() time.Time { return now }}
+	q := &countingExec{}
+	ctx := context.Background()
+
+	f.markDirty()
+	if !f.refresh(ctx, q) || q.n != 1 {
+		t.Fatalf("first search: rebuilds = %d, want 1", q.n)
+	}
+	for range 10 {
+		f.markDirty()
+		if !f.refresh(ctx, q) {
+			t.Fatal("stale index should stay usable")
+		}
+	}
+	if q.n != 1 {
+		t.Fatalf("writes within the interval: rebuilds = %d, want 1", q.n)
+	}
+
+	now = now.Add(ftsRefreshInterval)
+	f.refresh(ctx, q)
+	f.refresh(ctx, q)
+	if q.n != 2 {
+		t.Fatalf("after the interval: rebuilds = %d, want 2", q.n)
+	}
+}
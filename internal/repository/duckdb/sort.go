@@ -0,0 +1,81 @@
+package duckdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// orderBy renders keys as an ORDER BY list, ending with the id tiebreak.
+func orderBy(keys []filter.SortKey) (string, error) {
+	parts := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		if !filter.Sortable[k.Field] {
+			return "", fmt.Errorf("sort: unknown field %q", k.Field)
+		}
+		if k.Field == "id" {
+			break
+		}
+		dir := "ASC"
+		if k.Desc {
+			dir = "DESC"
+		}
+		parts = append(parts, k.Field+" "+dir)
+	}
+	dir := "ASC"
+	if k, ok := idKey(keys); ok && k.Desc {
+		dir = "DESC"
+	}
+	return strings.Join(append(parts, "id "+dir), ", "), nil
+}
+
+// keysetCondition selects the rows strictly after c in the order given by
+// keys, e.g. for "name,-creation_time":
+//
+//	name > ? OR (name = ? AND (creation_time < ? OR (creation_time = ? AND id > ?)))
+func keysetCondition(keys []filter.SortKey, c *repository.Cursor) (string, []any, error) {
+	if len(c.Values) != len(sortPrefix(keys)) {
+		return "", nil, fmt.Errorf("sort: cursor has %d values, want %d", len(c.Values), len(sortPrefix(keys)))
+	}
+
+	idOp := ">"
+	if k, ok := idKey(keys); ok && k.Desc {
+		idOp = "<"
+	}
+	cond := "id " + idOp + " ?"
+	args := []any{c.ID}
+
+	// Build from the innermost (id) condition outwards.
+	prefix := sortPrefix(keys)
+	for i := len(prefix) - 1; i >= 0; i-- {
+		op := ">"
+		if prefix[i].Desc {
+			op = "<"
+		}
+		col := prefix[i].Field
+		cond = col + " " + op + " ? OR (" + col + " = ? AND (" + cond + "))"
+		args = append([]any{c.Values[i], c.Values[i]}, args...)
+	}
+	return cond, args, nil
+}
+
+// sortPrefix returns the keys before id, which is always the final tiebreak.
+func sortPrefix(keys []filter.SortKey) []filter.SortKey {
+	for i, k := range keys {
+		if k.Field == "id" {
+			return keys[:i]
+		}
+	}
+	return keys
+}
+
+func idKey(keys []filter.SortKey) (filter.SortKey, bool) {
+	for _, k := range keys {
+		if k.Field == "id" {
+			return k, true
+		}
+	}
+	return filter.SortKey{}, false
+}
@@ -0,0 +1,131 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+var _ repository.StatsReader = (*deviceRepo)(nil)
+
+func (r *deviceRepo) Stats(ctx context.Context, granularity string) (*repository.DeviceStats, error) {
+	stats := &repository.DeviceStats{
+		ByBrand:      []repository.BrandCount{},
+		ByState:      []repository.StateCount{},
+		ByBrandState: []repository.BrandStateCount{},
+		Created:      []repository.TimeBucket{},
+		TimeInState:  []repository.StateDuration{},
+	}
+
+	// One pass over the table yields the grand total and every grouping;
+	// GROUPING() tells the sets apart (1 = column rolled up). Brands group
+	// case-insensitively, like the brand filter.
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT MIN(brand), state, GROUPING(brand_key), GROUPING(state), COUNT(*)
+		FROM (SELECT lower(brand) AS brand_key, brand, state FROM devices)
+		GROUP BY GROUPING SETS ((), (brand_key), (state), (brand_key, state))
+		ORDER BY GROUPING(brand_key), GROUPING(state), COUNT(*) DESC, brand_key, state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var brand, state sql.NullString
+		var gBrand, gState, n int
+		if err := rows.Scan(&brand, &state, &gBrand, &gState, &n); err != nil {
+			return nil, err
+		}
+		switch {
+		case gBrand == 1 && gState == 1:
+			stats.Total = n
+		case gState == 1:
+			stats.ByBrand = append(stats.ByBrand, repository.BrandCount{Brand: brand.String, Count: n})
+		case gBrand == 1:
+			stats.ByState = append(stats.ByState, repository.StateCount{State: state.String, Count: n})
+		default:
+			stats.ByBrandState = append(stats.ByBrandState, repository.BrandStateCount{Brand: brand.String, State: state.String, Count: n})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	buckets, err := r.q.QueryContext(ctx, `
+		SELECT date_trunc(?, creation_time) AS bucket, COUNT(*)
+		FROM devices
+		GROUP BY bucket
+		ORDER BY bucket`, granularity)
+	if err != nil {
+		return nil, err
+	}
+	defer buckets.Close()
+
+	for buckets.Next() {
+		var start string
+		var n int
+		if err := buckets.Scan(&start, &n); err != nil {
+			return nil, err
+		}
+		t, err := parseTime(start)
+		if err != nil {
+			return nil, err
+		}
+		stats.Created = append(stats.Created, repository.TimeBucket{Start: t, Count: n})
+	}
+	if err := buckets.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.TimeInState, err = r.timeInState(ctx, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// timeInState turns the change log into stays: only events that change a
+// device's state (or delete it) start or end one, so updates to other
+// fields do not split a stay. Once PruneEvents has run, a stay that began
+// before the oldest kept event counts from that event.
+func (r *deviceRepo) timeInState(ctx context.Context, now time.Time) ([]repository.StateDuration, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		WITH logged AS (
+			SELECT seq, device_id, type, occurred_at, payload->>'$.state' AS state,
+				LAG(payload->>'$.state') OVER (PARTITION BY device_id ORDER BY seq) AS prev_state
+			FROM device_events
+		), changes AS (
+			SELECT seq, device_id, type, occurred_at, state
+			FROM logged
+			WHERE type = 'deleted' OR prev_state IS NULL OR state <> prev_state
+		), stays AS (
+			SELECT state, type, LEAD(occurred_at) OVER (PARTITION BY device_id ORDER BY seq) IS NULL AS open,
+				date_diff('millisecond', occurred_at,
+					COALESCE(LEAD(occurred_at) OVER (PARTITION BY device_id ORDER BY seq), CAST(? AS TIMESTAMP))) AS ms
+			FROM changes
+		)
+		SELECT state, COUNT(*), COUNT(*) FILTER (WHERE open),
+			CAST(SUM(ms) AS DOUBLE), AVG(ms), quantile_cont(ms, 0.5), quantile_cont(ms, 0.9), CAST(MAX(ms) AS DOUBLE)
+		FROM stays
+		WHERE type <> 'deleted'
+		GROUP BY state
+		ORDER BY state`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ms := func(v float64) time.Duration { return time.Duration(v * float64(time.Millisecond)) }
+	list := []repository.StateDuration{}
+	for rows.Next() {
+		var sd repository.StateDuration
+		var total, avg, median, p90, max float64
+		if err := rows.Scan(&sd.State, &sd.Stays, &sd.Open, &total, &avg, &median, &p90, &max); err != nil {
+			return nil, err
+		}
+		sd.Total, sd.Average, sd.Median, sd.P90, sd.Max = ms(total), ms(avg), ms(median), ms(p90), ms(max)
+		list = append(list, sd)
+	}
+	return list, rows.Err()
+}
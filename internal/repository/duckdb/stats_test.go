@@ -0,0 +1,122 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+func TestTimeInStateFromChangeLog(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := NewDeviceRepository(db).(*deviceRepo)
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := []struct {
+		typ, device, state string
+		at                 time.Duration
+	}{
+		{"created", "a", "available", 0},
+		{"updated", "a", "available", time.Hour}, // renamed: same stay
+		{"updated", "a", "in-use", 2 * time.Hour},
+		{"updated", "a", "available", 5 * time.Hour},
+		{"created", "b", "available", 0},
+		{"updated", "b", "in-use", 4 * time.Hour},
+		{"deleted", "b", "in-use", 5 * time.Hour},
+	}
+	for _, e := range log {
+		if _, err := db.Exec(`INSERT INTO device_events (type, device_id, payload, occurred_at) VALUES (?, ?, ?, ?)`,
+			e.typ, e.device, `{"state":"`+e.state+`"}`, t0.Add(e.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.timeInState(context.Background(), t0.Add(6*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %+v, want available and in-use", got)
+	}
+	avail, inUse := got[0], got[1]
+	// a: 2h, then 1h still open; b: 4h.
+	if avail.State != "available" || avail.Stays != 3 || avail.Open != 1 || avail.Total != 7*time.Hour || avail.Max != 4*time.Hour || avail.Median != 2*time.Hour {
+		t.Errorf("available = %+v", avail)
+	}
+	// a: 3h; b: 1h, ended by the delete.
+	if inUse.State != "in-use" || inUse.Stays != 2 || inUse.Open != 0 || inUse.Total != 4*time.Hour || inUse.Average != 2*time.Hour {
+		t.Errorf("in-use = %+v", inUse)
+	}
+}
+
+func TestPruneEventsKeepsTheLatestEventOfLiveDevices(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := NewDeviceRepository(db).(*deviceRepo)
+	ctx := context.Background()
+
+	d, _ := device.New("Phone", "Acme")
+	if _, err := repo.Save(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`UPDATE device_events SET occurred_at = ?`, t0); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct{ typ, device string }{
+		{"updated", d.ID()}, // the live device's latest: kept though old
+		{"created", "gone"},
+		{"deleted", "gone"},
+	} {
+		if _, err := db.Exec(`INSERT INTO device_events (type, device_id, payload, occurred_at) VALUES (?, ?, '{}', ?)`,
+			e.typ, e.device, t0.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := repo.PruneEvents(ctx, t0.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("pruned %d events, want 3", n)
+	}
+	left, err := repo.DeviceHistory(ctx, d.ID(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].Type != "updated" {
+		t.Errorf("left %+v, want the update", left)
+	}
+}
+
+func TestStatsGroupBrandsCaseInsensitively(t *testing.T) {
+	repo := openRepo(t)
+	ctx := context.Background()
+	for _, brand := range []string{"Apple", "apple", "Google"} {
+		d, _ := device.New("Phone", brand)
+		if _, err := repo.Save(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := repo.(repository.StatsReader).Stats(ctx, repository.GranularityMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.ByBrand) != 2 || stats.ByBrand[0] != (repository.BrandCount{Brand: "Apple", Count: 2}) {
+		t.Errorf("by brand = %+v", stats.ByBrand)
+	}
+	if len(stats.ByBrandState) != 2 || stats.ByBrandState[0].Count != 2 {
+		t.Errorf("by brand and state = %+v", stats.ByBrandState)
+	}
+}
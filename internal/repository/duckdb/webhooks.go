@@ -0,0 +1,190 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/repository"
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+var _ repository.WebhookStore = (*deviceRepo)(nil)
+
+func (r *deviceRepo) CreateWebhook(ctx context.Context, s *webhook.Subscription) error {
+	return r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		if _, err := q.ExecContext(ctx,
+			`INSERT INTO webhooks (webhook_id, url, secret, created_at) VALUES (?, ?, ?, ?)`,
+			s.ID, s.URL, s.Secret, s.CreatedAt); err != nil {
+			return err
+		}
+		for _, t := range s.EventTypes {
+			if _, err := q.ExecContext(ctx,
+				`INSERT INTO webhook_event_types (webhook_id, event_type) VALUES (?, ?)`, s.ID, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *deviceRepo) Webhook(ctx context.Context, id string) (*webhook.Subscription, error) {
+	list, err := r.webhooks(ctx, `WHERE w.webhook_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("webhook", id)
+	}
+	return list[0], nil
+}
+
+func (r *deviceRepo) Webhooks(ctx context.Context) ([]*webhook.Subscription, error) {
+	return r.webhooks(ctx, ``)
+}
+
+func (r *deviceRepo) webhooks(ctx context.Context, where string, args ...any) ([]*webhook.Subscription, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT w.webhook_id, w.url, w.secret, w.created_at,
+			(SELECT string_agg(t.event_type, ',' ORDER BY t.event_type)
+			FROM webhook_event_types t WHERE t.webhook_id = w.webhook_id)
+		FROM webhooks w `+where+` ORDER BY w.created_at, w.webhook_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*webhook.Subscription
+	for rows.Next() {
+		var s webhook.Subscription
+		var types sql.NullString
+		if err := rows.Scan(&s.ID, &s.URL, &s.Secret, &s.CreatedAt, &types); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = s.CreatedAt.UTC()
+		if types.String != "" {
+			s.EventTypes = strings.Split(types.String, ",")
+		}
+		list = append(list, &s)
+	}
+	return list, rows.Err()
+}
+
+func (r *deviceRepo) DeleteWebhook(ctx context.Context, id string) error {
+	return r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		q := tx.(*deviceRepo).q
+		res, err := q.ExecContext(ctx, `DELETE FROM webhooks WHERE webhook_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return repository.NotFound("webhook", id)
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM webhook_event_types WHERE webhook_id = ?`, id); err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id)
+		return err
+	})
+}
+
+func (r *deviceRepo) EnqueueDelivery(ctx context.Context, d *webhook.Delivery) error {
+	return r.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		var exists bool
+		if err := tx.(*deviceRepo).q.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE webhook_id = ? AND event_id = ?)`,
+			d.SubscriptionID, d.EventID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		return tx.(*deviceRepo).SaveDelivery(ctx, d)
+	})
+}
+
+func (r *deviceRepo) SaveDelivery(ctx context.Context, d *webhook.Delivery) error {
+	var code any
+	if d.LastStatusCode != 0 {
+		code = d.LastStatusCode
+	}
+	// Attempts touch only non-key columns, so the update stays in place.
+	res, err := r.q.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?,
+			last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE delivery_id = ?`,
+		d.Status, d.Attempts, d.NextAttemptAt, code, nullIfEmpty(d.LastError), timeOrNil(d.DeliveredAt), d.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = r.q.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (delivery_id, webhook_id, event_id, event_type, payload, status, attempts,
+			next_attempt_at, last_status_code, last_error, created_at, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts,
+		d.NextAttemptAt, code, nullIfEmpty(d.LastError), d.CreatedAt, timeOrNil(d.DeliveredAt))
+	return err
+}
+
+func (r *deviceRepo) Delivery(ctx context.Context, webhookID, deliveryID string) (*webhook.Delivery, error) {
+	list, err := r.deliveries(ctx, `WHERE webhook_id = ? AND delivery_id = ?`, `created_at DESC`, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.NotFound("delivery", deliveryID)
+	}
+	return list[0], nil
+}
+
+func (r *deviceRepo) Deliveries(ctx context.Context, webhookID, status string) ([]*webhook.Delivery, error) {
+	if status != "" {
+		return r.deliveries(ctx, `WHERE webhook_id = ? AND status = ?`, `created_at DESC, delivery_id`, webhookID, status)
+	}
+	return r.deliveries(ctx, `WHERE webhook_id = ?`, `created_at DESC, delivery_id`, webhookID)
+}
+
+func (r *deviceRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*webhook.Delivery, error) {
+	return r.deliveries(ctx, `WHERE status = ? AND next_attempt_at <= ?`, `next_attempt_at, created_at LIMIT ?`,
+		webhook.StatusPending, now.UTC(), limit)
+}
+
+func (r *deviceRepo) deliveries(ctx context.Context, where, order string, args ...any) ([]*webhook.Delivery, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT delivery_id, webhook_id, event_id, event_type, CAST(payload AS VARCHAR), status, attempts,
+			next_attempt_at, last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries `+where+` ORDER BY `+order, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*webhook.Delivery
+	for rows.Next() {
+		var d webhook.Delivery
+		var payload string
+		var code sql.NullInt64
+		var lastErr sql.NullString
+		var delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &code, &lastErr, &d.CreatedAt, &delivered); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.LastStatusCode = int(code.Int64)
+		d.LastError = lastErr.String
+		d.NextAttemptAt = d.NextAttemptAt.UTC()
+		d.CreatedAt = d.CreatedAt.UTC()
+		if delivered.Valid {
+			t := delivered.Time.UTC()
+			d.DeliveredAt = &t
+		}
+		list = append(list, &d)
+	}
+	return list, rows.Err()
+}
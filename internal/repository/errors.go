@@ -0,0 +1,44 @@
+package repository
+
+import "errors"
+
+// Every backend reports missing records and rejected writes with these, so
+// callers can test for them without knowing the storage behind the
+// interface. Match them with errors.Is; the typed errors below carry the
+// details.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
+// NotFoundError reports a lookup of a record that does not exist. It
+// matches ErrNotFound.
+type NotFoundError struct {
+	// Resource names the kind of record, e.g. "device" or "webhook".
+	Resource string
+	ID       string
+}
+
+// NotFound returns a NotFoundError for the given resource and ID.
+func NotFound(resource, id string) error {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " " + e.ID + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ConflictError reports a write the storage rejected because it clashes
+// with existing data, such as a duplicate key. It matches ErrConflict and
+// unwraps to the driver error.
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string { return "conflict: " + e.Err.Error() }
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+func (e *ConflictError) Unwrap() error { return e.Err }
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Device event types.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// Event is one entry of the device change log.
+type Event struct {
+	Seq      int64
+	Type     string
+	DeviceID string
+	// Payload is the JSON device.Snapshot as of the change; for deletes, the
+	// device as it was before.
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+// EventLog reads the device change log, which the repository appends to in
+// the same transaction as every Save, Update and Delete. Sequence numbers
+// grow in commit order. Callers type-assert for it.
+type EventLog interface {
+	// EventsAfter returns up to limit events with Seq greater than after,
+	// oldest first.
+	EventsAfter(ctx context.Context, after int64, limit int) ([]Event, error)
+	// LastEventSeq returns the newest Seq, or 0 while the log is empty.
+	LastEventSeq(ctx context.Context) (int64, error)
+	// DeviceHistory returns up to limit of one device's events, newest
+	// first.
+	DeviceHistory(ctx context.Context, deviceID string, limit int) ([]Event, error)
+	// PruneEvents deletes events that occurred before cutoff, except the
+	// latest event of each device that still exists, and reports how many
+	// it deleted.
+	PruneEvents(ctx context.Context, cutoff time.Time) (int64, error)
+}
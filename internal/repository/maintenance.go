@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// MaintenanceStore keeps device service records. Callers type-assert for
+// it; inside WithTx the transaction-bound repository implements it too.
+// Lookups of unknown tickets return ErrNotFound.
+type MaintenanceStore interface {
+	// SaveTicket inserts a new ticket or records the closing of an
+	// existing one.
+	SaveTicket(ctx context.Context, t *device.MaintenanceTicket) error
+	// Ticket returns one of a device's tickets.
+	Ticket(ctx context.Context, deviceID, ticketID string) (*device.MaintenanceTicket, error)
+	// Tickets lists a device's tickets, newest first.
+	Tickets(ctx context.Context, deviceID string) ([]*device.MaintenanceTicket, error)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxMessage is a device change waiting to be relayed. The repository
+// writes one in the same transaction as every Save, Update and Delete.
+type OutboxMessage struct {
+	// ID is stable across redeliveries; consumers dedupe on it.
+	ID       string
+	Seq      int64
+	Type     string
+	DeviceID string
+	// PreviousState is the device's state before an update, else empty.
+	PreviousState string
+	// Payload is the JSON device.Snapshot, as in Event.
+	Payload []byte
+	// DomainEvents is a JSON array of the device.Events behind the change,
+	// each as {"type": EventName, "data": event}.
+	DomainEvents []byte
+	OccurredAt   time.Time
+	// Attempts counts the relay's failed attempts so far; RetryAt is when it
+	// may try again, zero for right away.
+	Attempts int
+	RetryAt  time.Time
+}
+
+// Outbox gives a relay the changes it has not dispatched yet. Callers
+// type-assert for it.
+type Outbox interface {
+	// PendingOutbox returns up to limit messages that are neither
+	// dispatched nor dead-lettered, in commit order.
+	PendingOutbox(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// MarkDispatched records that every sink has taken the message.
+	MarkDispatched(ctx context.Context, seq int64, at time.Time) error
+	// MarkFailed records a failed attempt. The message is retried from
+	// retryAt, or with dead set is dead-lettered: it stays in the outbox for
+	// inspection but is no longer pending.
+	MarkFailed(ctx context.Context, seq int64, reason string, retryAt time.Time, dead bool) error
+	// PruneOutbox deletes messages dispatched before cutoff and reports how
+	// many there were.
+	PruneOutbox(ctx context.Context, cutoff time.Time) (int64, error)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// ReservationQuery selects reservations. Zero fields do not filter; From
+// and To keep reservations overlapping [From, To).
+type ReservationQuery struct {
+	DeviceID string
+	Reserver string
+	Statuses []string
+	From     time.Time
+	To       time.Time
+}
+
+// ReservationStore keeps device reservations. Callers type-assert for it;
+// inside WithTx the transaction-bound repository implements it too. Lookups
+// of unknown reservations return ErrNotFound.
+type ReservationStore interface {
+	// CreateReservation stores a new reservation. It returns ErrNotFound
+	// when the device does not exist, and rejects one that overlaps a
+	// scheduled or active reservation of the same device with a
+	// device.ErrConflict.
+	CreateReservation(ctx context.Context, r *device.Reservation) error
+	// UpdateReservation records a status change.
+	UpdateReservation(ctx context.Context, r *device.Reservation) error
+	Reservation(ctx context.Context, id string) (*device.Reservation, error)
+	// Reservations lists matching reservations by start time.
+	Reservations(ctx context.Context, q ReservationQuery) ([]*device.Reservation, error)
+	// DueReservations lists the reservations whose state should change at
+	// now: scheduled ones that have started and active ones that have ended.
+	DueReservations(ctx context.Context, now time.Time) ([]*device.Reservation, error)
+}
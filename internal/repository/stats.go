@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Histogram granularities accepted by StatsReader.
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// BrandCount counts the devices of one brand. Brands group
+// case-insensitively, as the brand filter matches them; Brand is the
+// spelling that sorts first.
+type BrandCount struct {
+	Brand string
+	Count int
+}
+
+type StateCount struct {
+	State string
+	Count int
+}
+
+type BrandStateCount struct {
+	Brand string
+	State string
+	Count int
+}
+
+// TimeBucket counts devices created in [Start, Start+granularity).
+type TimeBucket struct {
+	Start time.Time
+	Count int
+}
+
+// StateDuration summarises the stays devices made in one state, read from
+// the change log: a stay starts when a device enters the state and ends
+// when it leaves it or is deleted. Stays still open are measured up to now.
+type StateDuration struct {
+	State   string
+	Stays   int
+	Open    int
+	Total   time.Duration
+	Average time.Duration
+	Median  time.Duration
+	P90     time.Duration
+	Max     time.Duration
+}
+
+type DeviceStats struct {
+	Total        int
+	ByBrand      []BrandCount
+	ByState      []StateCount
+	ByBrandState []BrandStateCount
+	Created      []TimeBucket
+	TimeInState  []StateDuration
+}
+
+// StatsReader is implemented by backends that can aggregate the fleet
+// in the database. Callers type-assert for it.
+type StatsReader interface {
+	Stats(ctx context.Context, granularity string) (*DeviceStats, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+// WebhookStore keeps webhook subscriptions and their delivery log. Callers
+// type-assert for it. Lookups of unknown subscriptions or deliveries return
+// ErrNotFound.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, s *webhook.Subscription) error
+	Webhook(ctx context.Context, id string) (*webhook.Subscription, error)
+	// Webhooks lists every subscription, oldest first.
+	Webhooks(ctx context.Context) ([]*webhook.Subscription, error)
+	// DeleteWebhook removes a subscription together with its deliveries.
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// EnqueueDelivery inserts d unless its subscription already has a
+	// delivery of the same event, so relaying an event twice is harmless.
+	EnqueueDelivery(ctx context.Context, d *webhook.Delivery) error
+	// SaveDelivery inserts a delivery or records an attempt on it.
+	SaveDelivery(ctx context.Context, d *webhook.Delivery) error
+	Delivery(ctx context.Context, webhookID, deliveryID string) (*webhook.Delivery, error)
+	// Deliveries lists a subscription's deliveries, newest first, optionally
+	// only those with the given status.
+	Deliveries(ctx context.Context, webhookID, status string) ([]*webhook.Delivery, error)
+	// DueDeliveries returns up to limit pending deliveries whose next attempt
+	// is at or before now, oldest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*webhook.Delivery, error)
+}
@@ -0,0 +1,114 @@
+// Package reservation runs the background side of device reservations.
+package reservation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// DefaultInterval is how often Run looks for reservations to start or end.
+const DefaultInterval = 30 * time.Second
+
+// Worker moves devices to in-use when a reservation starts and back when it
+// ends. A reservation whose device is still busy when it should start is
+// retried on every tick until it ends, and is then marked missed.
+type Worker struct {
+	repo     repository.DeviceRepository
+	interval time.Duration
+}
+
+// NewWorker returns a worker polling every interval; zero means
+// DefaultInterval.
+func NewWorker(repo repository.DeviceRepository, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Worker{repo: repo, interval: interval}
+}
+
+// Run ticks until ctx is cancelled. It does nothing if the repository does
+// not store reservations.
+func (w *Worker) Run(ctx context.Context) {
+	if _, ok := w.repo.(repository.ReservationStore); !ok {
+		return
+	}
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		if err := w.Tick(ctx, time.Now()); err != nil {
+			log.Printf("reservation worker: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick applies every reservation transition due at now. Each reservation is
+// handled in its own transaction so one failure does not hold up the rest.
+func (w *Worker) Tick(ctx context.Context, now time.Time) error {
+	store, ok := w.repo.(repository.ReservationStore)
+	if !ok {
+		return nil
+	}
+	due, err := store.DueReservations(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, res := range due {
+		if err := w.advance(ctx, res.ID, now); err != nil {
+			log.Printf("reservation worker: reservation %s: %v", res.ID, err)
+		}
+	}
+	return nil
+}
+
+func (w *Worker) advance(ctx context.Context, id string, now time.Time) error {
+	return w.repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		store := tx.(repository.ReservationStore)
+		// Re-read inside the transaction; a cancel may have won the race.
+		res, err := store.Reservation(ctx, id)
+		if err != nil {
+			return err
+		}
+		d, err := tx.FindByID(ctx, res.DeviceID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !now.Before(res.EndsAt):
+			var open *device.Assignment
+			if open, err = repository.OpenAssignmentOf(ctx, tx, d.ID()); err != nil {
+				return err
+			}
+			err = d.EndReservation(res, open)
+		case res.Status == device.ReservationScheduled:
+			err = d.StartReservation(res)
+			var derr *device.DomainError
+			if errors.As(err, &derr) && derr.Field == "device" {
+				// Still checked out or otherwise busy; try again next tick.
+				return nil
+			}
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Update(ctx, d); err != nil {
+			return err
+		}
+		return store.UpdateReservation(ctx, res)
+	})
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// cursorToken is the decoded form of the opaque cursor handed to clients. It
+// remembers the sort it was issued for so it cannot be replayed under a
+// different order.
+type cursorToken struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     string   `json:"id"`
+}
+
+func encodeCursor(keys []filter.SortKey, d *device.Device) string {
+	tok := cursorToken{Sort: filter.FormatSort(keys), ID: d.ID()}
+	for _, k := range keys {
+		if k.Field == "id" {
+			break
+		}
+		tok.Values = append(tok.Values, sortValue(d, k.Field))
+	}
+	b, _ := json.Marshal(tok)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, keys []filter.SortKey) (*repository.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor()
+	}
+	var tok cursorToken
+	if err := json.Unmarshal(b, &tok); err != nil || tok.ID == "" {
+		return nil, errInvalidCursor()
+	}
+	if tok.Sort != filter.FormatSort(keys) {
+		return nil, device.ErrInvalid("cursor", "cursor was issued for a different sort", http.StatusBadRequest)
+	}
+
+	// A cursor carries one value per key before id, which ends the order.
+	prefix := len(keys)
+	for i, k := range keys {
+		if k.Field == "id" {
+			prefix = i
+			break
+		}
+	}
+	if len(tok.Values) != prefix {
+		return nil, errInvalidCursor()
+	}
+
+	c := &repository.Cursor{ID: tok.ID}
+	for i, v := range tok.Values {
+		if filter.Fields[keys[i].Field].Kind == filter.KindTime {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, errInvalidCursor()
+			}
+			c.Values = append(c.Values, t)
+			continue
+		}
+		c.Values = append(c.Values, v)
+	}
+	return c, nil
+}
+
+// sortValue renders the sort key field of d as text, with times in
+// RFC 3339.
+func sortValue(d *device.Device, field string) string {
+	switch field {
+	case "name":
+		return d.Name()
+	case "brand":
+		return d.Brand()
+	case "state":
+		return d.State()
+	case "creation_time":
+		return d.CreationTime().UTC().Format(time.RFC3339Nano)
+	default:
+		return d.ID()
+	}
+}
+
+func errInvalidCursor() *device.DomainError {
+	return device.ErrInvalid("cursor", "cursor is malformed or expired", http.StatusBadRequest)
+}
@@ -0,0 +1,249 @@
+// Package service holds the device use cases shared by every frontend. It
+// validates input, applies the business rules, runs the transactions and
+// returns domain errors; transports only translate requests and responses.
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// DefaultTenant owns the attribute schema applied when a request names no
+// tenant.
+//
+// Tenants only select a schema: devices do not belong to one, and the
+// tenant comes from a header the client sets, so a client can pick another
+// tenant's schema, or none. Schemas keep honest clients consistent; they are
+// not an access control until requests carry an authenticated tenant.
+const DefaultTenant = "default"
+
+// Devices runs the device use cases against a repository.
+type Devices struct {
+	repo repository.DeviceRepository
+}
+
+func NewDevices(repo repository.DeviceRepository) *Devices {
+	return &Devices{repo: repo}
+}
+
+// CreateDeviceInput describes a new device. Empty State means available.
+type CreateDeviceInput struct {
+	// Tenant selects the attribute schema; empty means DefaultTenant.
+	Tenant       string
+	Name         string
+	Brand        string
+	State        string
+	SerialNumber string
+	Attributes   map[string]any
+	Tags         []string
+}
+
+// CreateDevice validates and stores a new device.
+func (s *Devices) CreateDevice(ctx context.Context, in CreateDeviceInput) (*device.Device, error) {
+	var (
+		d   *device.Device
+		err error
+	)
+	if strings.TrimSpace(in.State) == "" {
+		d, err = device.New(in.Name, in.Brand)
+	} else {
+		d, err = device.New(in.Name, in.Brand, in.State)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := device.NormalizeAttributes(in.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ValidateAttributes(ctx, in.Tenant, attrs, in.Attributes); err != nil {
+		return nil, err
+	}
+	d.SetAttributes(attrs)
+	if err := d.SetTags(in.Tags); err != nil {
+		return nil, err
+	}
+	if err := d.SetSerialNumber(in.SerialNumber); err != nil {
+		return nil, err
+	}
+	return s.repo.Save(ctx, d)
+}
+
+// GetDevice loads one device. With fields, only those response fields are
+// read and the device comes back partially populated.
+func (s *Devices) GetDevice(ctx context.Context, id string, fields []string) (*device.Device, error) {
+	if len(fields) == 0 {
+		d, err := s.repo.FindByID(ctx, id)
+		return d, notFound(err)
+	}
+	// Go through the list query so only the requested columns are read.
+	list, err := s.repo.FindAll(ctx, repository.ListOptions{
+		Filter: filter.Eq("id", id),
+		Fields: fields,
+		Limit:  1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrDeviceNotFound()
+	}
+	return list[0], nil
+}
+
+// UpdateDeviceInput is a partial update: nil fields, and blank name, brand
+// and state, are left alone.
+type UpdateDeviceInput struct {
+	// Tenant selects the attribute schema; empty means DefaultTenant.
+	Tenant string
+	ID     string
+	Name   *string
+	Brand  *string
+	State  *string
+	// An empty string clears the serial number.
+	SerialNumber *string
+	// Merged into the current attributes; a nil value removes the key.
+	Attributes map[string]any
+	// Replaces every tag when non-nil; an empty slice clears them.
+	Tags *[]string
+}
+
+// UpdateDevice applies in to a device. Reading, checking and writing share
+// one transaction so a concurrent request cannot flip the device to in-use
+// between the check and the update.
+func (s *Devices) UpdateDevice(ctx context.Context, in UpdateDeviceInput) (*device.Device, error) {
+	var updated *device.Device
+	err := s.repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		d, err := tx.FindByID(ctx, in.ID)
+		if err != nil {
+			return err
+		}
+
+		// Name and brand go first so they are still frozen when the same
+		// update takes the device out of use.
+		if in.Name != nil && strings.TrimSpace(*in.Name) != "" {
+			if err := d.SetName(*in.Name); err != nil {
+				return err
+			}
+		}
+		if in.Brand != nil && strings.TrimSpace(*in.Brand) != "" {
+			if err := d.SetBrand(*in.Brand); err != nil {
+				return err
+			}
+		}
+		if in.State != nil && strings.TrimSpace(*in.State) != "" {
+			open, err := repository.OpenAssignmentOf(ctx, tx, d.ID())
+			if err != nil {
+				return err
+			}
+			if err := d.SetState(*in.State, open); err != nil {
+				return err
+			}
+		}
+		if in.Attributes != nil {
+			merged := map[string]any{}
+			for k, v := range d.Attributes() {
+				merged[k] = v
+			}
+			for k, v := range in.Attributes {
+				if v == nil {
+					delete(merged, k)
+				} else {
+					merged[k] = v
+				}
+			}
+			attrs, err := device.NormalizeAttributes(merged)
+			if err != nil {
+				return err
+			}
+			if err := s.validateAttributes(ctx, tx, in.Tenant, attrs, in.Attributes); err != nil {
+				return err
+			}
+			d.SetAttributes(attrs)
+		}
+		if in.Tags != nil {
+			if err := d.SetTags(*in.Tags); err != nil {
+				return err
+			}
+		}
+		if in.SerialNumber != nil {
+			if err := d.SetSerialNumber(*in.SerialNumber); err != nil {
+				return err
+			}
+		}
+
+		updated, err = tx.Update(ctx, d)
+		return err
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return updated, nil
+}
+
+// DeleteDevice removes a device unless it is in use.
+func (s *Devices) DeleteDevice(ctx context.Context, id string) error {
+	err := s.repo.WithTx(ctx, func(tx repository.DeviceRepository) error {
+		d, err := tx.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := d.Delete(time.Now()); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, d)
+	})
+	return notFound(err)
+}
+
+// ValidateAttributes checks attrs against the tenant's attribute schema, if
+// the backend stores schemas and the tenant has one. sent holds the
+// attributes as the client wrote them; see device.AttributeSchema.Validate.
+func (s *Devices) ValidateAttributes(ctx context.Context, tenant string, attrs device.Attributes, sent map[string]any) error {
+	return s.validateAttributes(ctx, s.repo, tenant, attrs, sent)
+}
+
+func (s *Devices) validateAttributes(ctx context.Context, repo repository.DeviceRepository, tenant string, attrs device.Attributes, sent map[string]any) error {
+	store, ok := repo.(repository.AttributeSchemaStore)
+	if !ok {
+		return nil
+	}
+	if tenant = strings.TrimSpace(tenant); tenant == "" {
+		tenant = DefaultTenant
+	}
+	raw, err := store.AttributeSchema(ctx, tenant)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	schema, err := device.ParseAttributeSchema(raw)
+	if err != nil {
+		return err
+	}
+	return schema.Validate(attrs, sent)
+}
+
+// ErrDeviceNotFound is returned for unknown device IDs.
+func ErrDeviceNotFound() *device.DomainError {
+	return &device.DomainError{
+		Code: "not_found", Field: "id", Message: "device not found", HTTP: http.StatusNotFound,
+	}
+}
+
+// notFound turns repository.ErrNotFound into ErrDeviceNotFound.
+func notFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrDeviceNotFound()
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/filter"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// memRepo keeps devices in insertion order; list options other than Offset
+// and Limit are ignored, and unused methods hit the nil embedded interface.
+type memRepo struct {
+	repository.DeviceRepository
+	devices []*device.Device
+	// deleted holds the events pulled from the last device deleted.
+	deleted []device.Event
+}
+
+func (m *memRepo) Save(_ context.Context, d *device.Device) (*device.Device, error) {
+	m.devices = append(m.devices, d)
+	return d, nil
+}
+
+func (m *memRepo) FindByID(_ context.Context, id string) (*device.Device, error) {
+	for _, d := range m.devices {
+		if d.ID() == id {
+			return d, nil
+		}
+	}
+	return nil, repository.NotFound("device", id)
+}
+
+func (m *memRepo) FindAll(_ context.Context, opts repository.ListOptions) ([]*device.Device, error) {
+	list := m.devices[min(opts.Offset, len(m.devices)):]
+	if opts.Limit > 0 && len(list) > opts.Limit {
+		list = list[:opts.Limit]
+	}
+	return list, nil
+}
+
+func (m *memRepo) Update(_ context.Context, d *device.Device) (*device.Device, error) {
+	return d, nil
+}
+
+func (m *memRepo) Delete(_ context.Context, d *device.Device) error {
+	for i, kept := range m.devices {
+		if kept.ID() == d.ID() {
+			m.devices = append(m.devices[:i], m.devices[i+1:]...)
+			m.deleted = d.PullEvents()
+			return nil
+		}
+	}
+	return repository.NotFound("device", d.ID())
+}
+
+func (m *memRepo) WithTx(_ context.Context, fn func(tx repository.DeviceRepository) error) error {
+	return fn(m)
+}
+
+func errCode(err error) string {
+	var derr *device.DomainError
+	if errors.As(err, &derr) {
+		return derr.Code
+	}
+	return ""
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestUnknownDevicesAreNotFound(t *testing.T) {
+	s := NewDevices(&memRepo{})
+	ctx := context.Background()
+
+	if _, err := s.GetDevice(ctx, "missing", nil); errCode(err) != "not_found" {
+		t.Errorf("GetDevice: got %v", err)
+	}
+	if _, err := s.UpdateDevice(ctx, UpdateDeviceInput{ID: "missing", Name: strPtr("x")}); errCode(err) != "not_found" {
+		t.Errorf("UpdateDevice: got %v", err)
+	}
+	if err := s.DeleteDevice(ctx, "missing"); errCode(err) != "not_found" {
+		t.Errorf("DeleteDevice: got %v", err)
+	}
+}
+
+func TestDevicesInUseCannotBeRenamedOrDeleted(t *testing.T) {
+	repo := &memRepo{}
+	s := NewDevices(repo)
+	ctx := context.Background()
+
+	d, err := s.CreateDevice(ctx, CreateDeviceInput{Name: "Phone", Brand: "Acme", State: device.StateInUse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateDevice(ctx, UpdateDeviceInput{ID: d.ID(), Name: strPtr("Tablet")}); err == nil {
+		t.Error("renamed a device in use")
+	}
+	if err := s.DeleteDevice(ctx, d.ID()); errCode(err) != "conflict_device" {
+		t.Errorf("DeleteDevice: got %v", err)
+	}
+
+	// Leaving use in the same update still sees the name frozen.
+	_, err = s.UpdateDevice(ctx, UpdateDeviceInput{ID: d.ID(), Name: strPtr("Tablet"), State: strPtr(device.StateAvailable)})
+	if err == nil {
+		t.Error("renamed a device while taking it out of use")
+	}
+	if _, err := s.UpdateDevice(ctx, UpdateDeviceInput{ID: d.ID(), State: strPtr(device.StateAvailable)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteDevice(ctx, d.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.devices) != 0 {
+		t.Errorf("device was not deleted")
+	}
+	if n := len(repo.deleted); n == 0 || repo.deleted[n-1].EventName() != "DeviceDeleted" {
+		t.Errorf("the repository was not handed DeviceDeleted: %v", repo.deleted)
+	}
+}
+
+func TestUpdateMergesAttributes(t *testing.T) {
+	s := NewDevices(&memRepo{})
+	ctx := context.Background()
+
+	d, err := s.CreateDevice(ctx, CreateDeviceInput{
+		Name: "Phone", Brand: "Acme", Attributes: map[string]any{"color": "red", "ram_gb": 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.UpdateDevice(ctx, UpdateDeviceInput{
+		ID: d.ID(), Attributes: map[string]any{"color": nil, "storage_gb": 128},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := got.Attributes()
+	if _, ok := attrs["color"]; ok {
+		t.Errorf("color was not removed: %v", attrs)
+	}
+	if len(attrs) != 2 {
+		t.Errorf("attributes = %v, want ram_gb and storage_gb", attrs)
+	}
+}
+
+func TestListDevicesPages(t *testing.T) {
+	repo := &memRepo{}
+	s := NewDevices(repo)
+	ctx := context.Background()
+	for range 5 {
+		if _, err := s.CreateDevice(ctx, CreateDeviceInput{Name: "Phone", Brand: "Acme"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		in                ListDevicesInput
+		count, next, prev int
+	}{
+		{"defaults", ListDevicesInput{}, 5, 0, 0},
+		{"first page", ListDevicesInput{Page: 1, Limit: 2}, 2, 2, 0},
+		{"middle page", ListDevicesInput{Page: 2, Limit: 2}, 2, 3, 1},
+		{"last page", ListDevicesInput{Page: 3, Limit: 2}, 1, 0, 2},
+		{"unpaged", ListDevicesInput{Unpaged: true, Limit: 2}, 5, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.ListDevices(ctx, tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Devices) != tt.count || out.NextPage != tt.next || out.PreviousPage != tt.prev {
+				t.Errorf("got %d devices, next %d, prev %d; want %d, %d, %d",
+					len(out.Devices), out.NextPage, out.PreviousPage, tt.count, tt.next, tt.prev)
+			}
+		})
+	}
+}
+
+func TestListDevicesRejectsCursorsUnderRelevance(t *testing.T) {
+	s := NewDevices(&memRepo{})
+	in := ListDevicesInput{Options: repository.ListOptions{Search: "phone"}, Cursor: "abc"}
+	if _, err := s.ListDevices(context.Background(), in); errCode(err) != "invalid_cursor" {
+		t.Errorf("got %v, want invalid_cursor", err)
+	}
+}
+
+func TestDecodeCursorChecksValueCount(t *testing.T) {
+	keys := []filter.SortKey{{Field: "name"}, {Field: "creation_time", Desc: true}}
+	d, _ := device.New("Phone", "Acme")
+	if _, err := decodeCursor(encodeCursor(keys, d), keys); err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+
+	for _, values := range [][]string{nil, {"Phone"}, {"Phone", "2025-01-01T00:00:00Z", "extra"}} {
+		b, _ := json.Marshal(cursorToken{Sort: filter.FormatSort(keys), Values: values, ID: d.ID()})
+		if _, err := decodeCursor(base64.RawURLEncoding.EncodeToString(b), keys); errCode(err) != "invalid_cursor" {
+			t.Errorf("%d values: got %v, want invalid_cursor", len(values), err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/leandronowras/device-api/internal/device"
+	"github.com/leandronowras/device-api/internal/repository"
+)
+
+// Page sizes for ListDevices.
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// ListDevicesInput selects a listing. Unless Unpaged is set, it returns one
+// page: either page number Page (1-based) or the page after Cursor.
+type ListDevicesInput struct {
+	Options repository.ListOptions
+	// Unpaged returns every match at once; Page, Limit and Cursor are
+	// ignored.
+	Unpaged bool
+	// Page below 1 means the first page; Limit below 1 means
+	// DefaultPageSize, and it is capped at MaxPageSize.
+	Page   int
+	Limit  int
+	Cursor string
+}
+
+// ListDevicesOutput is one page of devices. NextPage and PreviousPage are
+// 0 when there is no such page, or when paging by cursor; NextCursor is
+// empty on the last page, or when the order cannot be resumed (relevance
+// ranking).
+type ListDevicesOutput struct {
+	Devices      []*device.Device
+	NextPage     int
+	PreviousPage int
+	NextCursor   string
+}
+
+func (s *Devices) ListDevices(ctx context.Context, in ListDevicesInput) (*ListDevicesOutput, error) {
+	opts := in.Options
+	if in.Unpaged {
+		list, err := s.repo.FindAll(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &ListDevicesOutput{Devices: list}, nil
+	}
+
+	page := in.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := in.Limit
+	if limit < 1 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	// Cursors resume behind the last device of the previous page; they need a
+	// deterministic sort, which relevance ranking is not.
+	keys := opts.Sort
+	if len(keys) == 0 {
+		keys = repository.DefaultSort
+	}
+	resumable := opts.Search == "" || len(opts.Sort) > 0
+	if in.Cursor != "" {
+		if !resumable {
+			return nil, device.ErrInvalid("cursor", "cursor pagination with q requires sort", http.StatusBadRequest)
+		}
+		after, err := decodeCursor(in.Cursor, keys)
+		if err != nil {
+			return nil, err
+		}
+		opts.After = after
+	} else {
+		opts.Offset = (page - 1) * limit
+	}
+	// Fetch one extra row to learn whether another page exists.
+	opts.Limit = limit + 1
+
+	list, err := s.repo.FindAll(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListDevicesOutput{Devices: list}
+	if len(list) > limit {
+		out.Devices = list[:limit]
+		if in.Cursor == "" {
+			out.NextPage = page + 1
+		}
+		if resumable {
+			out.NextCursor = encodeCursor(keys, out.Devices[limit-1])
+		}
+	}
+	if page > 1 && in.Cursor == "" {
+		out.PreviousPage = page - 1
+	}
+	return out, nil
+}
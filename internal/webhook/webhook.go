@@ -0,0 +1,289 @@
+// Package webhook describes outgoing webhook subscriptions and the deliveries
+// made to them.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leandronowras/device-api/internal/device"
+)
+
+// Event types a subscription can ask for. A device entering a state emits
+// device.state.<state> in addition to device.created or device.updated.
+const (
+	EventDeviceCreated = "device.created"
+	EventDeviceUpdated = "device.updated"
+	EventDeviceDeleted = "device.deleted"
+	stateEventPrefix   = "device.state."
+)
+
+// StateEvent is the event type emitted when a device enters state.
+func StateEvent(state string) string { return stateEventPrefix + state }
+
+func isEventType(t string) bool {
+	switch t {
+	case EventDeviceCreated, EventDeviceUpdated, EventDeviceDeleted,
+		StateEvent(device.StateAvailable), StateEvent(device.StateInUse),
+		StateEvent(device.StateInactive), StateEvent(device.StateMaintenance):
+		return true
+	}
+	return false
+}
+
+// Event is the body POSTed to subscribers.
+type Event struct {
+	// ID is the same on every delivery and redelivery of the event.
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	PreviousState string          `json:"previous_state,omitempty"`
+	Device        device.Snapshot `json:"device"`
+}
+
+// EventTypes maps a repository change (created, updated, deleted) to the
+// webhook event types it produces. previousState is empty unless the change
+// is an update.
+func EventTypes(change, previousState, state string) []string {
+	switch change {
+	case "created":
+		return []string{EventDeviceCreated, StateEvent(state)}
+	case "updated":
+		if previousState != "" && previousState != state {
+			return []string{EventDeviceUpdated, StateEvent(state)}
+		}
+		return []string{EventDeviceUpdated}
+	case "deleted":
+		return []string{EventDeviceDeleted}
+	}
+	return nil
+}
+
+// EventID derives the ID of the eventType event produced by the change
+// with the given message ID, so relaying a change twice yields the same ID.
+func EventID(messageID, eventType string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(messageID+"#"+eventType)).String()
+}
+
+// minSecret is the shortest accepted signing secret.
+const minSecret = 16
+
+// AllowPrivateTargets lets subscriptions and deliveries reach loopback,
+// private and link-local addresses. It is off by default so webhook URLs
+// cannot be used to reach the internal network; main turns it on with
+// WEBHOOK_ALLOW_PRIVATE=true for local development.
+var AllowPrivateTargets = false
+
+// cgnat is the shared address space of carrier-grade NAT, RFC 6598.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckTargetIP returns an error unless deliveries may connect to ip: a
+// public unicast address, or any address with AllowPrivateTargets.
+func CheckTargetIP(ip netip.Addr) error {
+	if AllowPrivateTargets {
+		return nil
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || cgnat.Contains(ip) {
+		return errors.New("webhook target " + ip.String() + " is not a public address")
+	}
+	return nil
+}
+
+// checkTargetHost rejects URL hosts that name a non-public address outright.
+// Host names are checked again when a delivery connects (see CheckTargetIP),
+// since only then is it known what they resolve to.
+func checkTargetHost(host string) error {
+	if AllowPrivateTargets {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("localhost")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return CheckTargetIP(ip)
+	}
+	return nil
+}
+
+// Subscription asks for events of EventTypes to be POSTed to URL, signed
+// with Secret.
+type Subscription struct {
+	ID         string
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+// NewSubscription validates and returns a subscription.
+func NewSubscription(target, secret string, eventTypes []string, now time.Time) (*Subscription, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, device.ErrRequired("url")
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, device.ErrInvalid("url", "url must be an absolute http or https URL", http.StatusBadRequest)
+	}
+	if checkTargetHost(u.Hostname()) != nil {
+		return nil, device.ErrInvalid("url", "url must not point at a loopback, private or link-local address", http.StatusBadRequest)
+	}
+	if len(secret) < minSecret {
+		return nil, device.ErrInvalid("secret", "secret must be at least 16 characters", http.StatusBadRequest)
+	}
+
+	seen := map[string]struct{}{}
+	for _, t := range eventTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !isEventType(t) {
+			return nil, device.ErrInvalid("event_types", "unknown event type "+strconv.Quote(t), http.StatusBadRequest)
+		}
+		seen[t] = struct{}{}
+	}
+	if len(seen) == 0 {
+		return nil, device.ErrRequired("event_types")
+	}
+	types := make([]string, 0, len(seen))
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return &Subscription{
+		ID:         uuid.NewString(),
+		URL:        target,
+		Secret:     secret,
+		EventTypes: types,
+		CreatedAt:  now.UTC(),
+	}, nil
+}
+
+// Wants reports whether the subscription asked for events of type t.
+func (s *Subscription) Wants(t string) bool {
+	for _, want := range s.EventTypes {
+		if want == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead marks a delivery that failed MaxAttempts times.
+	StatusDead = "dead"
+)
+
+// MaxAttempts is how often a delivery is tried before it is dead-lettered.
+const MaxAttempts = 5
+
+// Delivery is one event on its way to one subscription. EventID is shared by
+// the deliveries of the same event to different subscriptions, so receivers
+// can dedupe on it.
+type Delivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// NewDelivery queues payload for s, due immediately.
+func NewDelivery(s *Subscription, eventID, eventType string, payload []byte, now time.Time) *Delivery {
+	return &Delivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: s.ID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         StatusPending,
+		NextAttemptAt:  now.UTC(),
+		CreatedAt:      now.UTC(),
+	}
+}
+
+// Succeeded records a 2xx answer.
+func (d *Delivery) Succeeded(code int, now time.Time) {
+	now = now.UTC()
+	d.Attempts++
+	d.Status = StatusDelivered
+	d.LastStatusCode = code
+	d.LastError = ""
+	d.DeliveredAt = &now
+}
+
+// Failed records a failed attempt; code is 0 when no response arrived. The
+// next attempt is scheduled with exponential backoff, or the delivery is
+// dead-lettered once it has used up MaxAttempts.
+func (d *Delivery) Failed(code int, reason string, now time.Time) {
+	d.Attempts++
+	d.LastStatusCode = code
+	if len(reason) > 500 {
+		reason = reason[:500]
+	}
+	d.LastError = reason
+	if d.Attempts >= MaxAttempts {
+		d.Status = StatusDead
+		return
+	}
+	d.NextAttemptAt = now.UTC().Add(Backoff(d.Attempts))
+}
+
+// Redeliver puts a dead or delivered delivery back in the queue with a
+// fresh set of attempts.
+func (d *Delivery) Redeliver(now time.Time) {
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = now.UTC()
+	d.DeliveredAt = nil
+}
+
+// Backoff is the wait after the given number of failed attempts: 10s, 20s,
+// 40s, ... capped at one hour.
+func Backoff(failures int) time.Duration {
+	const base, ceiling = 10 * time.Second, time.Hour
+	if failures < 1 {
+		return base
+	}
+	d := base
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= ceiling {
+			return ceiling
+		}
+	}
+	return d
+}
+
+// Signature returns the X-Webhook-Signature value for body sent at
+// timestamp: "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with secret. Covering the timestamp lets receivers reject replays.
+func Signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+const secret = "0123456789abcdef"
+
+func TestNewSubscriptionValidates(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name   string
+		url    string
+		secret string
+		types  []string
+	}{
+		{"no url", "", secret, []string{EventDeviceCreated}},
+		{"relative url", "/hooks", secret, []string{EventDeviceCreated}},
+		{"ftp url", "ftp://example.com/hooks", secret, []string{EventDeviceCreated}},
+		{"short secret", "https://example.com/hooks", "short", []string{EventDeviceCreated}},
+		{"no types", "https://example.com/hooks", secret, nil},
+		{"unknown type", "https://example.com/hooks", secret, []string{"device.exploded"}},
+		{"localhost", "http://localhost:8080/hooks", secret, []string{EventDeviceCreated}},
+		{"loopback", "http://127.0.0.1/hooks", secret, []string{EventDeviceCreated}},
+		{"private", "http://10.1.2.3/hooks", secret, []string{EventDeviceCreated}},
+		{"link-local", "http://169.254.169.254/latest/meta-data", secret, []string{EventDeviceCreated}},
+		{"ipv6 loopback", "http://[::1]/hooks", secret, []string{EventDeviceCreated}},
+		{"mapped private", "http://[::ffff:192.168.0.1]/hooks", secret, []string{EventDeviceCreated}},
+	}
+	for _, c := range cases {
+		if _, err := NewSubscription(c.url, c.secret, c.types, now); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+
+	s, err := NewSubscription("https://example.com/hooks", secret,
+		[]string{" Device.State.Inactive ", EventDeviceCreated, EventDeviceCreated}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.EventTypes) != 2 || !s.Wants("device.state.inactive") || !s.Wants(EventDeviceCreated) || s.Wants(EventDeviceDeleted) {
+		t.Fatalf("event types = %v", s.EventTypes)
+	}
+}
+
+func TestCheckTargetIP(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		if err := CheckTargetIP(netip.MustParseAddr(addr)); err != nil {
+			t.Errorf("%s: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"127.0.0.1", "10.0.0.1", "172.16.5.4", "192.168.1.1", "100.64.0.1", "169.254.169.254", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		if err := CheckTargetIP(netip.MustParseAddr(addr)); err == nil {
+			t.Errorf("%s: expected an error", addr)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second}
+	for i, w := range want {
+		if got := Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := Backoff(50); got != time.Hour {
+		t.Errorf("Backoff(50) = %v, want 1h", got)
+	}
+}
+
+func TestDeliveryDeadLettersAfterMaxAttempts(t *testing.T) {
+	now := time.Now()
+	s, _ := NewSubscription("https://example.com/hooks", secret, []string{EventDeviceCreated}, now)
+	d := NewDelivery(s, "evt", EventDeviceCreated, []byte(`{}`), now)
+
+	for i := 1; i < MaxAttempts; i++ {
+		d.Failed(500, "server error", now)
+		if d.Status != StatusPending {
+			t.Fatalf("attempt %d: status %s", i, d.Status)
+		}
+		if want := now.UTC().Add(Backoff(i)); !d.NextAttemptAt.Equal(want) {
+			t.Fatalf("attempt %d: next attempt %v, want %v", i, d.NextAttemptAt, want)
+		}
+	}
+	d.Failed(0, "connection refused", now)
+	if d.Status != StatusDead || d.Attempts != MaxAttempts {
+		t.Fatalf("status %s after %d attempts", d.Status, d.Attempts)
+	}
+
+	d.Redeliver(now)
+	if d.Status != StatusPending || d.Attempts != 0 {
+		t.Fatalf("redeliver: status %s, attempts %d", d.Status, d.Attempts)
+	}
+	d.Succeeded(204, now)
+	if d.Status != StatusDelivered || d.DeliveredAt == nil || d.LastError != "" {
+		t.Fatalf("succeeded: %+v", d)
+	}
+	d.Redeliver(now)
+	if d.Status != StatusPending || d.DeliveredAt != nil {
+		t.Fatalf("redeliver after success: %+v", d)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"type":"device.created"}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Signature(secret, 1700000000, body); got != want {
+		t.Fatalf("Signature = %s, want %s", got, want)
+	}
+	if Signature(secret, 1700000001, body) == want {
+		t.Fatal("signature does not cover the timestamp")
+	}
+}
+
+func TestEventTypes(t *testing.T) {
+	cases := []struct {
+		change, previous, state string
+		want                    string
+	}{
+		{"created", "", "available", "[device.created device.state.available]"},
+		{"updated", "available", "available", "[device.updated]"},
+		{"updated", "available", "inactive", "[device.updated device.state.inactive]"},
+		{"deleted", "", "inactive", "[device.deleted]"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(EventTypes(c.change, c.previous, c.state)); got != c.want {
+			t.Errorf("EventTypes(%q, %q, %q) = %s, want %s", c.change, c.previous, c.state, got, c.want)
+		}
+	}
+}
+
+func TestEventIDIsStable(t *testing.T) {
+	a := EventID("msg-1", EventDeviceUpdated)
+	if a != EventID("msg-1", EventDeviceUpdated) {
+		t.Fatal("EventID is not deterministic")
+	}
+	if a == EventID("msg-1", StateEvent("inactive")) || a == EventID("msg-2", EventDeviceUpdated) {
+		t.Fatal("EventID collides")
+	}
+}
@@ -0,0 +1,19 @@
+package bdd
+
+import "fmt"
+
+// And the device's checkout is past due
+//
+// Checkout refuses due dates in the past, so the fixture rewinds the stored
+// due date instead.
+func (w *apiWorld) theDevicesCheckoutIsPastDue() error {
+	res, err := w.db.Exec(
+		`UPDATE device_assignments SET due_at = CAST(now() AS TIMESTAMP) - INTERVAL 1 DAY WHERE device_id = ? AND checked_in_at IS NULL`, w.lastID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return fmt.Errorf("expected one open checkout for %s, found %d", w.lastID, n)
+	}
+	return nil
+}
@@ -6,19 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/cucumber/godog"
 )
 
 func (w *apiWorld) iPOSTWithJSON(path string, doc *godog.DocString) error {
-	resp, err := http.Post(w.server.URL+path, "application/json", bytes.NewBufferString(doc.Content))
-	if err != nil {
-		return err
-	}
-	w.resp = resp
-	w.body, _ = io.ReadAll(resp.Body)
-	_ = resp.Body.Close()
-	return nil
+	return w.iPOSTWithContentType(path, "application/json", doc)
 }
 
 func (w *apiWorld) theResponseCodeShouldBe(code int) error {
@@ -63,3 +57,37 @@ func (w *apiWorld) theResponseJsonHasKeys(key1, key2, key3 string) error {
 	}
 	return nil
 }
+
+func (w *apiWorld) iPOSTWithContentType(path, contentType string, doc *godog.DocString) error {
+	path = strings.ReplaceAll(path, "{id}", w.lastID)
+	body := strings.ReplaceAll(doc.Content, "{id}", w.lastID)
+	if w.receiver != nil {
+		body = strings.ReplaceAll(body, "{receiver}", w.receiver.server.URL)
+	}
+	resp, err := http.Post(w.server.URL+path, contentType, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	w.resp = resp
+	w.body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
+
+// And I save the response body
+func (w *apiWorld) iSaveTheResponseBody() error {
+	w.saved = append([]byte(nil), w.body...)
+	return nil
+}
+
+// When I POST the saved body to "/v1/devices/import.parquet" as "application/vnd.apache.parquet"
+func (w *apiWorld) iPOSTTheSavedBody(path, contentType string) error {
+	resp, err := http.Post(w.server.URL+path, contentType, bytes.NewReader(w.saved))
+	if err != nil {
+		return err
+	}
+	w.resp = resp
+	w.body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
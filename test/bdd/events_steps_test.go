@@ -0,0 +1,53 @@
+package bdd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The stream never ends on its own, so steps read it for a short while.
+const streamReadFor = 700 * time.Millisecond
+
+// When I read the event stream "/v1/devices/events" from event "0"
+func (w *apiWorld) iReadTheEventStreamFrom(path, lastEventID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), streamReadFor)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.server.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	w.resp = resp
+	// Reading stops with a context error once the deadline passes.
+	w.body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Then the event stream types should be "created,updated,deleted"
+func (w *apiWorld) theEventStreamTypesShouldBe(want string) error {
+	var got []string
+	sc := bufio.NewScanner(bytes.NewReader(w.body))
+	for sc.Scan() {
+		if t, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			got = append(got, t)
+		}
+	}
+	if strings.Join(got, ",") != want {
+		return fmt.Errorf("expected event types %q, got %q (body=%s)", want, strings.Join(got, ","), string(w.body))
+	}
+	return nil
+}
@@ -80,6 +80,604 @@ Feature: http API
     When I DELETE "/v1/devices/{id}"
     Then the response code should be 204
 
+  @id=9
+  Scenario: In use devices cannot be deleted
+    Given a device exists with name "iPhone" and brand "Apple"
+    And I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "in-use" }
+      """
+    When I DELETE "/v1/devices/{id}"
+    Then the response code should be 409
+    And the response json at "$.code" should be "conflict_device"
+
+  @id=10
+  Scenario: Name and brand cannot be updated while in use
+    Given a device exists with name "iPhone" and brand "Apple"
+    And I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "in-use" }
+      """
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "name": "iPhone 15" }
+      """
+    Then the response code should be 400
+    And the response json at "$.code" should be "forbidden_change"
+
+  @id=11
+  Scenario: Export devices as CSV
+    Given a device exists with name "iPhone" and brand "Apple"
+    And a device exists with name "Galaxy" and brand "Samsung"
+    When I GET "/v1/devices?brand=Apple" accepting "text/csv"
+    Then the response code should be 200
+    And the response header "Content-Type" should be "text/csv"
+    And the response body should have 2 lines
+
+  @id=12
+  Scenario: Export devices as NDJSON
+    Given a device exists with name "iPhone" and brand "Apple"
+    And a device exists with name "Galaxy" and brand "Samsung"
+    When I GET "/v1/devices" accepting "application/x-ndjson"
+    Then the response code should be 200
+    And the response header "Content-Type" should be "application/x-ndjson"
+    And the response body should have 2 lines
+
+  @id=13
+  Scenario: Bulk import devices from CSV
+    When I POST "/v1/devices:import" with "text/csv":
+      """
+      name,brand,state
+      iPhone,Apple,
+      Galaxy,Samsung,in-use
+      ,Google,
+      """
+    Then the response code should be 200
+    And the response json at "$.total" should be "3"
+    And the response json at "$.created" should be "2"
+    And the response json at "$.failed" should be "1"
+    When I GET "/v1/devices?state=in-use"
+    Then the response json should contain 1 device
+
+  @id=14
+  Scenario: Dry-run import does not persist devices
+    When I POST "/v1/devices:import?dry_run=true" with "application/x-ndjson":
+      """
+      {"name": "iPhone", "brand": "Apple"}
+      {"name": "Pixel", "brand": "Google"}
+      """
+    Then the response code should be 200
+    And the response json at "$.created" should be "2"
+    When I GET "/v1/devices"
+    Then the response json should contain 0 devices
+
+  @id=15
+  Scenario: Upsert import updates existing devices by id
+    Given a device exists with name "iPhone" and brand "Apple"
+    When I POST "/v1/devices:import?mode=upsert" with "application/x-ndjson":
+      """
+      {"id": "{id}", "name": "iPhone 15", "brand": "Apple"}
+      """
+    Then the response code should be 200
+    And the response json at "$.updated" should be "1"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.name" should be "iPhone 15"
+
+  @id=38
+  Scenario: Import cannot put a device in maintenance
+    When I POST "/v1/devices:import" with "application/x-ndjson":
+      """
+      {"id": "0b6f3f7e-6a43-4b8e-9a55-3f1d2c7a9e10", "name": "Pixel", "brand": "Google", "state": "maintenance"}
+      """
+    Then the response code should be 200
+    And the response json at "$.failed" should be "1"
+    And the response json at "$.created" should be "0"
+    When I GET "/v1/devices/0b6f3f7e-6a43-4b8e-9a55-3f1d2c7a9e10"
+    Then the response code should be 404
+
+  @id=39
+  Scenario: A failed import row leaves nothing behind
+    When I POST "/v1/devices:import" with "text/csv":
+      """
+      name,brand,serial_number
+      Fairphone 5,Fairphone,FP-0001
+      Fairphone 4,Fairphone,fp-0001
+      """
+    Then the response code should be 200
+    And the response json at "$.created" should be "1"
+    And the response json at "$.failed" should be "1"
+    When I GET "/v1/devices?brand=Fairphone"
+    Then the response json should contain 1 device
+
+  @id=40
+  Scenario: A checked-out device cannot leave use without a check-in
+    Given a device exists with name "iPad" and brand "Apple"
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "alice@example.com", "due_at": "2999-01-01T00:00:00Z" }
+      """
+    Then the response code should be 200
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "available" }
+      """
+    Then the response code should be 409
+    And the response json at "$.field" should be "state"
+    When I GET "/v1/devices/{id}/assignments"
+    Then the response json at "$[0].checked_in_at" should be "<nil>"
+
+  @id=16
+  Scenario: Parquet export round-trips through import
+    Given a device exists with name "iPhone" and brand "Apple"
+    When I GET "/v1/devices/export.parquet"
+    Then the response code should be 200
+    And the response header "Content-Type" should be "application/vnd.apache.parquet"
+    And I save the response body
+    When I POST the saved body to "/v1/devices/import.parquet" as "application/vnd.apache.parquet"
+    Then the response code should be 409
+    When I DELETE "/v1/devices/{id}"
+    And I POST the saved body to "/v1/devices/import.parquet" as "application/vnd.apache.parquet"
+    Then the response code should be 200
+    And the response json at "$.imported" should be "1"
+    When I GET "/v1/devices/{id}"
+    Then the response code should be 200
+    And the response json at "$.name" should be "iPhone"
+
+  @id=17
+  Scenario: Fleet statistics grouped by brand and state
+    Given a device exists with name "iPhone" and brand "Apple"
+    And a device exists with name "MacBook" and brand "Apple"
+    And a device exists with name "Galaxy" and brand "Samsung"
+    When I GET "/v1/devices/stats?granularity=month"
+    Then the response code should be 200
+    And the response json at "$.total" should be "3"
+    And the response json has keys: "by_brand", "by_state", "created"
+    And the response json has keys: "total", "by_brand_state", "time_in_state"
+
+  @id=18
+  Scenario: Fleet statistics reject unknown granularity
+    When I GET "/v1/devices/stats?granularity=year"
+    Then the response code should be 400
+    And the response json at "$.field" should be "granularity"
+
+  @id=19
+  Scenario: Search devices by name and brand prefix
+    Given a device exists with name "iPhone 15 Pro" and brand "Apple"
+    And a device exists with name "ThinkPad X1" and brand "Lenovo"
+    And a device exists with name "Galaxy S24" and brand "Samsung"
+    When I GET "/v1/devices?q=thinkp*"
+    Then the response code should be 200
+    And the response json should contain 1 device
+    And the response json at "$[0].name" should be "ThinkPad X1"
+
+  @id=20
+  Scenario: Search tolerates typos and requires every term to match
+    Given a device exists with name "iPhone 15 Pro" and brand "Apple"
+    And a device exists with name "iPhone 14" and brand "Apple"
+    And a device exists with name "Galaxy S24" and brand "Samsung"
+    When I GET "/v1/devices?q=ipone%2015&page=1&limit=10"
+    Then the response code should be 200
+    And the response json should contain 1 device
+    When I GET "/v1/devices?q=iphone"
+    Then the response json should contain 2 devices
+
+  @id=21
+  Scenario: Filter devices with a filter expression
+    Given a device exists with name "iPhone" and brand "Apple"
+    And a device exists with name "Galaxy S24" and brand "Samsung"
+    And a device exists with name "Galaxy Tab" and brand "Samsung"
+    And I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "inactive" }
+      """
+    When I GET "/v1/devices?filter=state%20in%20(available,in-use)%20and%20created_after=2025-01-01%20and%20name~%22galaxy%22"
+    Then the response code should be 200
+    And the response json should contain 1 device
+    And the response json at "$[0].name" should be "Galaxy S24"
+
+  @id=22
+  Scenario: Filter expressions reject unknown fields
+    When I GET "/v1/devices?filter=color=red"
+    Then the response code should be 400
+    And the response json at "$.code" should be "invalid_filter"
+
+  @id=23
+  Scenario: Sort devices by several fields
+    Given a device exists with name "Pixel" and brand "Google"
+    And a device exists with name "Galaxy" and brand "Samsung"
+    And a device exists with name "iPhone" and brand "Apple"
+    When I GET "/v1/devices?sort=brand,-creation_time"
+    Then the response code should be 200
+    And the response json at "$[0].brand" should be "Apple"
+    When I GET "/v1/devices?sort=-name&page=1&limit=2"
+    Then the response json should contain 2 devices
+    When I GET "/v1/devices?sort=color"
+    Then the response code should be 400
+    And the response json at "$.code" should be "invalid_sort"
+
+  @id=24
+  Scenario: Cursor pagination follows the requested sort
+    Given a device exists with name "Alpha" and brand "Acme"
+    And a device exists with name "Bravo" and brand "Acme"
+    And a device exists with name "Charlie" and brand "Acme"
+    When I GET "/v1/devices?sort=name&limit=2"
+    Then the response json should contain 2 devices
+    When I GET "/v1/devices?sort=name&limit=2" after the returned cursor
+    Then the response code should be 200
+    And the response json should contain 1 device
+    And the response json at "$.next_cursor" should be ""
+
+  @id=25
+  Scenario: Sparse fieldsets limit the serialized fields
+    Given a device exists with name "iPhone" and brand "Apple"
+    When I GET "/v1/devices?fields=id,state"
+    Then the response code should be 200
+    And the response json at "$[0].state" should be "available"
+    And the response json at "$[0].name" should be "<nil>"
+    When I GET "/v1/devices/{id}?fields=name"
+    Then the response code should be 200
+    And the response json at "$.name" should be "iPhone"
+    And the response json at "$.brand" should be "<nil>"
+    When I GET "/v1/devices?fields=id,serial"
+    Then the response code should be 400
+    And the response json at "$.code" should be "invalid_fields"
+
+  @id=26
+  Scenario: Devices carry typed custom attributes
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "iPhone", "brand": "Apple", "attributes": { "os_version": "17.1", "purchase_date": "2024-05-01", "managed": true } }
+      """
+    Then the response code should be 201
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "Pixel", "brand": "Google", "attributes": { "os_version": "14" } }
+      """
+    Then the response code should be 201
+    When I GET "/v1/devices?attr.os_version=17.1"
+    Then the response code should be 200
+    And the response json should contain 1 device
+    And the response json at "$[0].name" should be "iPhone"
+    When I GET "/v1/devices?filter=attr.managed=true%20or%20attr.os_version%3C15"
+    Then the response json should contain 2 devices
+
+  @id=27
+  Scenario: Tenant attribute schemas validate device attributes
+    When I PUT "/v1/tenants/default/attribute-schema" with json:
+      """
+      { "properties": { "os_version": { "type": "string" } }, "required": ["os_version"] }
+      """
+    Then the response code should be 204
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "iPhone", "brand": "Apple" }
+      """
+    Then the response code should be 422
+    And the response json at "$.field" should be "attributes.os_version"
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "iPhone", "brand": "Apple", "attributes": { "os_version": "17.1" } }
+      """
+    Then the response code should be 201
+
+  @id=28
+  Scenario: Devices can be tagged and listed by tag
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "Pixel", "brand": "Google", "tags": ["Project:Atlas", "berlin"] }
+      """
+    Then the response code should be 201
+    And the response json at "$.tags" should be "[berlin project:atlas]"
+    Given a device exists with name "iPhone" and brand "Apple"
+    When I POST "/v1/devices/{id}/tags/project:atlas" with json:
+      """
+      {}
+      """
+    Then the response code should be 200
+    And the response json at "$.tags" should be "[project:atlas]"
+    When I GET "/v1/devices?tag=project:atlas"
+    Then the response json should contain 2 devices
+    When I GET "/v1/devices?tag=project:atlas&tag=berlin"
+    Then the response json should contain 1 device
+    And the response json at "$[0].name" should be "Pixel"
+    When I GET "/v1/devices?any_tag=berlin,lisbon"
+    Then the response json should contain 1 device
+    When I DELETE "/v1/devices/{id}/tags/project:atlas"
+    Then the response code should be 200
+    When I GET "/v1/devices?tag=project:atlas"
+    Then the response json should contain 1 device
+    When I POST "/v1/devices/{id}/tags/no%20spaces" with json:
+      """
+      {}
+      """
+    Then the response code should be 400
+    And the response json at "$.code" should be "invalid_tag"
+
+  @id=29
+  Scenario: Serial numbers are unique per brand and can be looked up
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "ThinkPad X1", "brand": "Lenovo", "serial_number": "PF-3XK12" }
+      """
+    Then the response code should be 201
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "ThinkPad T14", "brand": "lenovo", "serial_number": "pf-3xk12" }
+      """
+    Then the response code should be 409
+    And the response json at "$.code" should be "conflict_serial_number"
+    When I POST "/v1/devices" with json:
+      """
+      { "name": "Pixel", "brand": "Google", "serial_number": "PF-3XK12" }
+      """
+    Then the response code should be 201
+    When I GET "/v1/devices/by-serial/Lenovo/PF-3XK12"
+    Then the response code should be 200
+    And the response json at "$.name" should be "ThinkPad X1"
+    When I POST "/v1/devices:import?mode=upsert" with "application/x-ndjson":
+      """
+      {"name": "ThinkPad X1 Gen 12", "brand": "Lenovo", "serial_number": "PF-3XK12"}
+      {"name": "ThinkPad T14", "brand": "Lenovo", "serial_number": "PF-9ZZ01"}
+      """
+    Then the response code should be 200
+    And the response json at "$.updated" should be "1"
+    And the response json at "$.created" should be "1"
+    When I GET "/v1/devices/by-serial/lenovo/pf-3xk12"
+    Then the response json at "$.name" should be "ThinkPad X1 Gen 12"
+    When I GET "/v1/devices/by-serial/Lenovo/NOPE-1"
+    Then the response code should be 404
+
+  @id=30
+  Scenario: Devices are checked out and in with an assignment history
+    Given a device exists with name "iPhone" and brand "Apple"
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "alice@example.com", "due_at": "2999-01-01T00:00:00Z" }
+      """
+    Then the response code should be 200
+    And the response json at "$.assignee" should be "alice@example.com"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "in-use"
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "bob@example.com", "due_at": "2999-01-01T00:00:00Z" }
+      """
+    Then the response code should be 409
+    When I GET "/v1/devices?overdue=true"
+    Then the response json should contain 0 devices
+    Given the device's checkout is past due
+    When I GET "/v1/devices?overdue=true"
+    Then the response json should contain 1 device
+    When I POST "/v1/devices/{id}/checkin" with json:
+      """
+      {}
+      """
+    Then the response code should be 200
+    And the response json at "$.overdue" should be "false"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "available"
+    When I GET "/v1/devices?filter=overdue=true"
+    Then the response json should contain 0 devices
+    When I GET "/v1/devices/{id}/assignments"
+    Then the response code should be 200
+    And the response json should contain 1 device
+    And the response json at "$[0].assignee" should be "alice@example.com"
+
+  @id=31
+  Scenario: Reservations block overlapping bookings and drive the device state
+    Given a device exists with name "Pixel 8" and brand "Google"
+    When I POST "/v1/devices/{id}/reservations" with json:
+      """
+      { "reserver": "qa-team", "starts_at": "2999-01-01T10:00:00Z", "ends_at": "2999-01-01T12:00:00Z" }
+      """
+    Then the response code should be 201
+    And the response json at "$.status" should be "scheduled"
+    When I POST "/v1/devices/{id}/reservations" with json:
+      """
+      { "reserver": "mobile-team", "starts_at": "2999-01-01T11:00:00Z", "ends_at": "2999-01-01T13:00:00Z" }
+      """
+    Then the response code should be 409
+    And the response json at "$.code" should be "conflict_reservation"
+    When I POST "/v1/devices/{id}/reservations" with json:
+      """
+      { "reserver": "mobile-team", "starts_at": "2999-01-01T12:00:00Z", "ends_at": "2999-01-01T13:00:00Z" }
+      """
+    Then the response code should be 201
+    When I GET "/v1/devices/{id}/free-busy?from=2999-01-01T09:00:00Z&to=2999-01-01T14:00:00Z"
+    Then the response code should be 200
+    And the response json at "$.free" should be "[map[end:2999-01-01T10:00:00Z start:2999-01-01T09:00:00Z] map[end:2999-01-01T14:00:00Z start:2999-01-01T13:00:00Z]]"
+    When the reservation worker runs at "2999-01-01T10:30:00Z"
+    And I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "in-use"
+    When the reservation worker runs at "2999-01-01T12:00:30Z"
+    And I GET "/v1/devices/{id}/reservations?status=completed"
+    Then the response json should contain 1 device
+    And the response json at "$[0].reserver" should be "qa-team"
+    When I cancel the device's "active" reservation
+    Then the response code should be 200
+    And the response json at "$.status" should be "cancelled"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "available"
+
+  @id=37
+  Scenario: A reservation ending does not release a device checked out during it
+    Given a device exists with name "Pixel 8" and brand "Google"
+    When I POST "/v1/devices/{id}/reservations" with json:
+      """
+      { "reserver": "qa-team", "starts_at": "2999-01-01T10:00:00Z", "ends_at": "2999-01-01T12:00:00Z" }
+      """
+    Then the response code should be 201
+    When the reservation worker runs at "2999-01-01T10:30:00Z"
+    And I POST "/v1/devices/{id}/checkin" with json:
+      """
+      {}
+      """
+    Then the response code should be 204
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "bob@example.com", "due_at": "2999-01-02T10:00:00Z" }
+      """
+    Then the response code should be 200
+    When the reservation worker runs at "2999-01-01T12:00:30Z"
+    And I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "in-use"
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "carol@example.com", "due_at": "2999-01-02T10:00:00Z" }
+      """
+    Then the response code should be 409
+
+  @id=32
+  Scenario: Maintenance tickets block checkout and restore the previous state
+    Given a device exists with name "Galaxy S24" and brand "Samsung"
+    When I POST "/v1/devices/{id}/maintenance-tickets" with json:
+      """
+      { "description": "Cracked screen", "cost": 89.5 }
+      """
+    Then the response code should be 201
+    And the response json at "$.previous_state" should be "available"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "maintenance"
+    When I POST "/v1/devices/{id}/checkout" with json:
+      """
+      { "assignee": "alice@example.com", "due_at": "2999-01-01T00:00:00Z" }
+      """
+    Then the response code should be 409
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "available" }
+      """
+    Then the response code should be 409
+    When I close the device's open maintenance ticket with json:
+      """
+      { "resolution": "Screen replaced", "cost": 129.9 }
+      """
+    Then the response code should be 200
+    And the response json at "$.cost" should be "129.9"
+    When I GET "/v1/devices/{id}"
+    Then the response json at "$.state" should be "available"
+    When I GET "/v1/devices/{id}/maintenance-tickets"
+    Then the response json should contain 1 device
+    And the response json at "$[0].resolution" should be "Screen replaced"
+
+  @id=33
+  Scenario: The device event stream replays changes after Last-Event-ID
+    Given a device exists with name "Galaxy S24" and brand "Samsung"
+    And a device exists with name "iPhone 15" and brand "Apple"
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "name": "iPhone 15 Pro" }
+      """
+    And I DELETE "/v1/devices/{id}"
+    And I read the event stream "/v1/devices/events?brand=apple" from event "0"
+    Then the response code should be 200
+    And the response header "Content-Type" should be "text/event-stream"
+    And the event stream types should be "created,updated,deleted"
+    When I read the event stream "/v1/devices/events" from event "2"
+    Then the event stream types should be "updated,deleted"
+    When I read the event stream "/v1/devices/events" from event ""
+    Then the event stream types should be ""
+    When I read the event stream "/v1/devices/events" from event "abc"
+    Then the response code should be 400
+
+  @id=34
+  Scenario: WebSocket clients receive updates for the devices they subscribe to
+    Given a device exists with name "Pixel 8" and brand "Google"
+    And the outbox relay has caught up
+    When I open a websocket to "/v1/devices/socket"
+    And I send on the websocket:
+      """
+      { "type": "subscribe", "device_ids": ["{id}"] }
+      """
+    And I receive a websocket message
+    Then the response json at "$.type" should be "subscribed"
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "name": "Pixel 8 Pro" }
+      """
+    And I receive a websocket message
+    Then the response json at "$.type" should be "event"
+    And the response json at "$.event" should be "updated"
+    When I send on the websocket:
+      """
+      { "type": "snapshot" }
+      """
+    And I receive a websocket message
+    Then the response json at "$.type" should be "snapshot"
+    When I send on the websocket:
+      """
+      { "type": "unsubscribe", "device_ids": ["{id}"] }
+      """
+    And I receive a websocket message
+    Then the response json at "$.type" should be "unsubscribed"
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "name": "Pixel 8a" }
+      """
+    And I send on the websocket:
+      """
+      { "type": "heartbeat" }
+      """
+    And I receive a websocket message
+    Then the response json at "$.type" should be "heartbeat"
+    When I send on the websocket:
+      """
+      not json
+      """
+    And I receive a websocket message
+    Then the response json at "$.code" should be "invalid_json"
+
+  @id=35
+  Scenario: A webhook subscriber is told when a device goes inactive
+    Given a webhook receiver answering 204
+    When I POST "/v1/webhooks" with json:
+      """
+      { "url": "{receiver}/hooks", "secret": "s3cr3t-s3cr3t-s3cr3t", "event_types": ["device.state.inactive"] }
+      """
+    Then the response code should be 201
+    And the response json at "$.event_types" should be "[device.state.inactive]"
+    Given a device exists with name "ThinkPad X1" and brand "Lenovo"
+    When I PATCH "/v1/devices/{id}" with json:
+      """
+      { "name": "ThinkPad X1 Carbon" }
+      """
+    And I PATCH "/v1/devices/{id}" with json:
+      """
+      { "state": "inactive" }
+      """
+    And the webhook worker runs
+    Then the receiver should have got 1 request signed with "s3cr3t-s3cr3t-s3cr3t"
+    And the receiver's last request should be a "device.state.inactive" event
+    When I GET the webhook's deliveries
+    Then the response json should contain 1 device
+    And the response json at "$[0].status" should be "delivered"
+    And the response json at "$[0].attempts" should be "1"
+
+  @id=36
+  Scenario: Failing webhook deliveries are retried, dead-lettered and can be redelivered
+    Given a webhook receiver answering 503
+    When I POST "/v1/webhooks" with json:
+      """
+      { "url": "{receiver}/hooks", "secret": "s3cr3t-s3cr3t-s3cr3t", "event_types": ["device.created"] }
+      """
+    Then the response code should be 201
+    Given a device exists with name "Galaxy Tab" and brand "Samsung"
+    When the webhook worker runs
+    And the webhook worker runs 4 more times an hour apart
+    Then the receiver should have got 5 requests signed with "s3cr3t-s3cr3t-s3cr3t"
+    When I GET the webhook's deliveries
+    Then the response json at "$[0].status" should be "dead"
+    And the response json at "$[0].attempts" should be "5"
+    And the response json at "$[0].last_status_code" should be "503"
+    Given the webhook receiver now answers 200
+    When I redeliver the webhook's dead delivery
+    Then the response code should be 200
+    And the response json at "$.status" should be "pending"
+    When the webhook worker runs
+    Then the receiver should have got 6 requests signed with "s3cr3t-s3cr3t-s3cr3t"
+    When I GET the webhook's deliveries
+    Then the response json at "$[0].status" should be "delivered"
+
 ##| 7 | Feature: Fully update a device (PUT /v1/devices/{id}) | pending | medium | None | N/A |
 ##| 8 | Feature: Partially update a device (PATCH /v1/devices/{id}) | pending | medium | None | N/A |
 ##| 9 | Feature: Delete a device (DELETE /v1/devices/{id}) | pending | medium | None | N/A |
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/cucumber/godog"
@@ -157,3 +158,54 @@ func (w *apiWorld) responseJsonAtShouldBe(path, expected string) error {
 
 	return fmt.Errorf("unsupported JSON path: %s", path)
 }
+
+// When I GET "/v1/devices" accepting "text/csv"
+func (w *apiWorld) iGETAccepting(path, accept string) error {
+	req, err := http.NewRequest(http.MethodGet, w.server.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	w.resp = resp
+	w.body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Then the response header "Content-Type" should be "text/csv"
+func (w *apiWorld) theResponseHeaderShouldBe(name, expected string) error {
+	if w.resp == nil {
+		return fmt.Errorf("no response")
+	}
+	if got := w.resp.Header.Get(name); got != expected {
+		return fmt.Errorf("header %s: want %q, got %q", name, expected, got)
+	}
+	return nil
+}
+
+// Then the response body should have {n} lines
+func (w *apiWorld) theResponseBodyShouldHaveNLines(n int) error {
+	lines := strings.Split(strings.TrimRight(string(w.body), "\n"), "\n")
+	if len(lines) != n {
+		return fmt.Errorf("expected %d lines, got %d (body=%s)", n, len(lines), string(w.body))
+	}
+	return nil
+}
+
+// When I GET "/v1/devices?limit=2&sort=name" after the returned cursor
+func (w *apiWorld) iGETAfterTheReturnedCursor(path string) error {
+	var obj map[string]any
+	if err := json.Unmarshal(w.body, &obj); err != nil {
+		return fmt.Errorf("invalid json: %w; body=%s", err, string(w.body))
+	}
+	cursor, _ := obj["next_cursor"].(string)
+	if cursor == "" {
+		return fmt.Errorf(`missing "next_cursor" in %s`, string(w.body))
+	}
+	return w.iGET(path + "&cursor=" + url.QueryEscape(cursor))
+}
@@ -22,6 +22,7 @@ func InitializeScenario(sc *godog.ScenarioContext) {
 		w.resp = nil
 		w.body = nil
 		w.lastID = ""
+		w.saved = nil
 
 		// Ensure no old server is dangling, then start a fresh one
 		w.stopServer()
@@ -36,12 +37,35 @@ func InitializeScenario(sc *godog.ScenarioContext) {
 		w.resp = nil
 		w.body = nil
 		w.lastID = ""
+		w.saved = nil
 		return ctx, nil
 	})
 
 	sc.Step(`^I POST "([^"]*)" with json:$`, w.iPOSTWithJSON)
+	sc.Step(`^I POST "([^"]*)" with "([^"]*)":$`, w.iPOSTWithContentType)
+	sc.Step(`^I save the response body$`, w.iSaveTheResponseBody)
+	sc.Step(`^I POST the saved body to "([^"]*)" as "([^"]*)"$`, w.iPOSTTheSavedBody)
 	sc.Step(`^I PATCH "([^"]*)" with json:$`, w.iPATCHWithJSON)
+	sc.Step(`^I PUT "([^"]*)" with json:$`, w.iPUTWithJSON)
 	sc.Step(`^I DELETE "([^"]*)"$`, w.iDELETE)
+	sc.Step(`^the device's checkout is past due$`, w.theDevicesCheckoutIsPastDue)
+	sc.Step(`^the reservation worker runs at "([^"]*)"$`, w.theReservationWorkerRunsAt)
+	sc.Step(`^I cancel the device's "([^"]*)" reservation$`, w.iCancelTheDevicesReservation)
+	sc.Step(`^I close the device's open maintenance ticket with json:$`, w.iCloseTheDevicesOpenTicket)
+	sc.Step(`^I read the event stream "([^"]*)" from event "([^"]*)"$`, w.iReadTheEventStreamFrom)
+	sc.Step(`^the event stream types should be "([^"]*)"$`, w.theEventStreamTypesShouldBe)
+	sc.Step(`^I open a websocket to "([^"]*)"$`, w.iOpenAWebsocketTo)
+	sc.Step(`^I send on the websocket:$`, w.iSendOnTheWebsocket)
+	sc.Step(`^I receive a websocket message$`, w.iReceiveAWebsocketMessage)
+	sc.Step(`^a webhook receiver answering (\d+)$`, w.aWebhookReceiverAnswering)
+	sc.Step(`^the webhook receiver now answers (\d+)$`, w.theWebhookReceiverNowAnswers)
+	sc.Step(`^the outbox relay has caught up$`, w.theOutboxRelayHasCaughtUp)
+	sc.Step(`^the webhook worker runs$`, w.theWebhookWorkerRuns)
+	sc.Step(`^the webhook worker runs (\d+) more times an hour apart$`, w.theWebhookWorkerRunsMoreTimes)
+	sc.Step(`^the receiver should have got (\d+) requests? signed with "([^"]*)"$`, w.theReceiverShouldHaveGotSignedRequests)
+	sc.Step(`^the receiver's last request should be a "([^"]*)" event$`, w.theReceiversLastRequestShouldBeAEvent)
+	sc.Step(`^I GET the webhook's deliveries$`, w.iGETTheWebhooksDeliveries)
+	sc.Step(`^I redeliver the webhook's dead delivery$`, w.iRedeliverTheWebhooksDeadDelivery)
 	sc.Step(`^the response code should be (\d+)$`, w.theResponseCodeShouldBe)
 	sc.Step(`^the response json at "([^"]*)" should be "([^"]*)"$`, w.responseJsonAtShouldBe)
 	sc.Step(`^the response json has keys: "([^"]*)", "([^"]*)", "([^"]*)"$`, w.theResponseJsonHasKeys)
@@ -58,6 +82,10 @@ func InitializeScenario(sc *godog.ScenarioContext) {
 	sc.Step(`^the response json should include "next_page" and "previous_page" fields$`, w.theResponseJSONShouldIncludeNextPrev)
 	sc.Step(`^the API is running$`, theAPIIsRunning)
 	sc.Step(`^the response json should contain (\d+) device[s]?$`, w.theResponseJSONShouldContainNDevices)
+	sc.Step(`^I GET "([^"]*)" after the returned cursor$`, w.iGETAfterTheReturnedCursor)
+	sc.Step(`^I GET "([^"]*)" accepting "([^"]*)"$`, w.iGETAccepting)
+	sc.Step(`^the response header "([^"]*)" should be "([^"]*)"$`, w.theResponseHeaderShouldBe)
+	sc.Step(`^the response body should have (\d+) lines$`, w.theResponseBodyShouldHaveNLines)
 }
 
 func TestMain(m *testing.M) {
@@ -0,0 +1,28 @@
+package bdd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cucumber/godog"
+)
+
+// When I close the device's open maintenance ticket with json:
+func (w *apiWorld) iCloseTheDevicesOpenTicket(doc *godog.DocString) error {
+	if err := w.iGET("/v1/devices/{id}/maintenance-tickets"); err != nil {
+		return err
+	}
+	var list []struct {
+		ID       string  `json:"id"`
+		ClosedAt *string `json:"closed_at"`
+	}
+	if err := json.Unmarshal(w.body, &list); err != nil {
+		return fmt.Errorf("invalid tickets json: %w (body=%s)", err, string(w.body))
+	}
+	for _, t := range list {
+		if t.ClosedAt == nil {
+			return w.iPOSTWithJSON("/v1/devices/{id}/maintenance-tickets/"+t.ID+"/close", doc)
+		}
+	}
+	return fmt.Errorf("device has no open maintenance ticket")
+}
@@ -0,0 +1,34 @@
+package bdd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// When the reservation worker runs at "2999-01-01T10:30:00Z"
+func (w *apiWorld) theReservationWorkerRunsAt(at string) error {
+	now, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return err
+	}
+	return w.worker.Tick(context.Background(), now)
+}
+
+// When I cancel the device's "active" reservation
+func (w *apiWorld) iCancelTheDevicesReservation(status string) error {
+	if err := w.iGET("/v1/devices/{id}/reservations?status=" + status); err != nil {
+		return err
+	}
+	var list []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.body, &list); err != nil {
+		return fmt.Errorf("invalid reservations json: %w (body=%s)", err, string(w.body))
+	}
+	if len(list) != 1 {
+		return fmt.Errorf("expected one %s reservation, got %d", status, len(list))
+	}
+	return w.iDELETE("/v1/reservations/" + list[0].ID)
+}
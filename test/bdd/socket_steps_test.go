@@ -0,0 +1,46 @@
+package bdd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cucumber/godog"
+	"github.com/gorilla/websocket"
+)
+
+// When I open a websocket to "/v1/devices/socket"
+func (w *apiWorld) iOpenAWebsocketTo(path string) error {
+	url := "ws" + strings.TrimPrefix(w.server.URL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w (status=%v)", url, err, statusCode(resp))
+	}
+	w.ws = conn
+	return nil
+}
+
+// When I send on the websocket:
+func (w *apiWorld) iSendOnTheWebsocket(doc *godog.DocString) error {
+	if w.ws == nil {
+		return fmt.Errorf("no websocket open")
+	}
+	msg := strings.ReplaceAll(doc.Content, "{id}", w.lastID)
+	return w.ws.WriteMessage(websocket.TextMessage, []byte(msg))
+}
+
+// Then I receive a websocket message
+//
+// The message becomes the response body, so the json steps apply to it.
+func (w *apiWorld) iReceiveAWebsocketMessage() error {
+	if w.ws == nil {
+		return fmt.Errorf("no websocket open")
+	}
+	_ = w.ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := w.ws.ReadMessage()
+	if err != nil {
+		return fmt.Errorf("no websocket message: %w", err)
+	}
+	w.body = data
+	return nil
+}
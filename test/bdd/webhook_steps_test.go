@@ -0,0 +1,183 @@
+package bdd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leandronowras/device-api/internal/webhook"
+)
+
+// webhookReceiver is a stand-in for a subscriber's endpoint.
+type webhookReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []receivedHook
+}
+
+type receivedHook struct {
+	header http.Header
+	body   []byte
+}
+
+// Given a webhook receiver answering 204
+func (w *apiWorld) aWebhookReceiverAnswering(status int) error {
+	rcv := &webhookReceiver{status: status}
+	rcv.server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.requests = append(rcv.requests, receivedHook{header: r.Header.Clone(), body: body})
+		rw.WriteHeader(rcv.status)
+	}))
+	w.receiver = rcv
+	return nil
+}
+
+// Given the webhook receiver now answers 200
+func (w *apiWorld) theWebhookReceiverNowAnswers(status int) error {
+	if w.receiver == nil {
+		return fmt.Errorf("no webhook receiver running")
+	}
+	w.receiver.mu.Lock()
+	defer w.receiver.mu.Unlock()
+	w.receiver.status = status
+	return nil
+}
+
+// Given the outbox relay has caught up
+func (w *apiWorld) theOutboxRelayHasCaughtUp() error {
+	return w.relay.Tick(context.Background())
+}
+
+// When the webhook worker runs
+func (w *apiWorld) theWebhookWorkerRuns() error {
+	// Queue whatever the relay has not picked up yet.
+	if err := w.theOutboxRelayHasCaughtUp(); err != nil {
+		return err
+	}
+	if w.clock.IsZero() {
+		w.clock = time.Now()
+	}
+	return w.deliveries.Tick(context.Background(), w.clock)
+}
+
+// When the webhook worker runs 4 more times an hour apart
+//
+// An hour is longer than any backoff, so every run retries what failed.
+func (w *apiWorld) theWebhookWorkerRunsMoreTimes(n int) error {
+	for i := 0; i < n; i++ {
+		w.clock = w.clock.Add(time.Hour)
+		if err := w.deliveries.Tick(context.Background(), w.clock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Then the receiver should have got 1 request signed with "..."
+func (w *apiWorld) theReceiverShouldHaveGotSignedRequests(n int, secret string) error {
+	if w.receiver == nil {
+		return fmt.Errorf("no webhook receiver running")
+	}
+	w.receiver.mu.Lock()
+	defer w.receiver.mu.Unlock()
+
+	if len(w.receiver.requests) != n {
+		return fmt.Errorf("expected %d requests, got %d", n, len(w.receiver.requests))
+	}
+	for i, req := range w.receiver.requests {
+		ts, err := strconv.ParseInt(req.header.Get("X-Webhook-Timestamp"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("request %d: bad timestamp: %w", i, err)
+		}
+		if got, want := req.header.Get("X-Webhook-Signature"), webhook.Signature(secret, ts, req.body); got != want {
+			return fmt.Errorf("request %d: signature %q, want %q", i, got, want)
+		}
+	}
+	return nil
+}
+
+// Then the receiver's last request should be a "device.state.inactive" event
+func (w *apiWorld) theReceiversLastRequestShouldBeAEvent(eventType string) error {
+	if w.receiver == nil {
+		return fmt.Errorf("no webhook receiver running")
+	}
+	w.receiver.mu.Lock()
+	defer w.receiver.mu.Unlock()
+	if len(w.receiver.requests) == 0 {
+		return fmt.Errorf("receiver got no requests")
+	}
+	last := w.receiver.requests[len(w.receiver.requests)-1]
+	if got := last.header.Get("X-Webhook-Event"); got != eventType {
+		return fmt.Errorf("expected X-Webhook-Event %q, got %q", eventType, got)
+	}
+	var body struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(last.body, &body); err != nil || body.Type != eventType {
+		return fmt.Errorf("expected body of type %q, got %s", eventType, string(last.body))
+	}
+	return nil
+}
+
+// When I GET the webhook's deliveries
+func (w *apiWorld) iGETTheWebhooksDeliveries() error {
+	id, err := w.onlyWebhookID()
+	if err != nil {
+		return err
+	}
+	return w.iGET("/v1/webhooks/" + id + "/deliveries")
+}
+
+// When I redeliver the webhook's dead delivery
+func (w *apiWorld) iRedeliverTheWebhooksDeadDelivery() error {
+	id, err := w.onlyWebhookID()
+	if err != nil {
+		return err
+	}
+	if err := w.iGET("/v1/webhooks/" + id + "/deliveries?status=dead"); err != nil {
+		return err
+	}
+	var list []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.body, &list); err != nil {
+		return fmt.Errorf("invalid deliveries json: %w (body=%s)", err, string(w.body))
+	}
+	if len(list) != 1 {
+		return fmt.Errorf("expected 1 dead delivery, got %d", len(list))
+	}
+	resp, err := http.Post(w.server.URL+"/v1/webhooks/"+id+"/deliveries/"+list[0].ID+"/redeliver", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	w.resp = resp
+	w.body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (w *apiWorld) onlyWebhookID() (string, error) {
+	if err := w.iGET("/v1/webhooks"); err != nil {
+		return "", err
+	}
+	var list []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.body, &list); err != nil {
+		return "", fmt.Errorf("invalid webhooks json: %w (body=%s)", err, string(w.body))
+	}
+	if len(list) != 1 {
+		return "", fmt.Errorf("expected 1 webhook, got %d", len(list))
+	}
+	return list[0].ID, nil
+}
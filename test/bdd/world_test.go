@@ -1,15 +1,23 @@
 package bdd
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
 	_ "github.com/marcboeker/go-duckdb"
 
+	"github.com/leandronowras/device-api/internal/delivery"
+	"github.com/leandronowras/device-api/internal/graphql"
 	ih "github.com/leandronowras/device-api/internal/http"
+	"github.com/leandronowras/device-api/internal/outbox"
 	duckdbrepo "github.com/leandronowras/device-api/internal/repository/duckdb"
+	"github.com/leandronowras/device-api/internal/reservation"
+	"github.com/leandronowras/device-api/internal/webhook"
 )
 
 type apiWorld struct {
@@ -17,7 +25,17 @@ type apiWorld struct {
 	resp   *http.Response
 	body   []byte
 	lastID string
+	saved  []byte
 	db     *sql.DB
+	worker *reservation.Worker
+	ws     *websocket.Conn
+
+	relay      *outbox.Relay
+	stopRelay  context.CancelFunc
+	deliveries *delivery.Worker
+	receiver   *webhookReceiver
+	// clock is the time the webhook worker last ran at.
+	clock time.Time
 }
 
 func (w *apiWorld) theAPIIsRunning() error {
@@ -27,24 +45,40 @@ func (w *apiWorld) theAPIIsRunning() error {
 	}
 	w.db = db
 
+	// The webhook receiver listens on localhost.
+	webhook.AllowPrivateTargets = true
+
 	repo := duckdbrepo.NewDeviceRepository(db)
+	w.worker = reservation.NewWorker(repo, 0)
+	w.deliveries = delivery.NewWorker(repo, 0)
 
 	r := chi.NewRouter()
 	h := ih.NewHandler(repo)
 
-	r.Route("/v1", func(r chi.Router) {
-		r.Post("/devices", h.CreateDevice)
-		r.Get("/devices", h.ListDevices)
-		r.Get("/devices/{id}", h.GetDevice)
-		r.Patch("/devices/{id}", h.UpdateDevice)
-		r.Delete("/devices/{id}", h.DeleteDevice)
-	})
+	// Relay often so WebSocket clients see changes quickly; webhook steps
+	// also tick it themselves.
+	w.relay = outbox.NewRelay(repo, 20*time.Millisecond, outbox.NewBusSink(h.Bus()), outbox.NewWebhookSink(repo))
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopRelay = cancel
+	go w.relay.Run(ctx)
+
+	r.Route("/v1", h.Routes)
+	r.Handle("/graphql", graphql.NewHandler(repo, h.Bus()))
 
 	w.server = httptest.NewServer(r)
 	return nil
 }
 
 func (w *apiWorld) stopServer() {
+	if w.stopRelay != nil {
+		w.stopRelay()
+	}
+	if w.ws != nil {
+		w.ws.Close()
+	}
+	if w.receiver != nil {
+		w.receiver.server.Close()
+	}
 	if w.server != nil {
 		w.server.Close()
 	}